@@ -0,0 +1,248 @@
+/*
+Copyright © 2020 Axway, Inc. <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/skckadiyala/apimanager/apimgr"
+	"github.com/skckadiyala/kubecrt-vms/utils"
+	"github.com/spf13/cobra"
+)
+
+var (
+	accessApp   string
+	accessProxy string
+)
+
+// accessCmd represents the access command
+var (
+	accessCmd = &cobra.Command{
+		Use:   "access",
+		Short: "Manage the proxies an application can call",
+		Long: `Manage the proxies an application can call.
+
+For example:
+
+apimanager access list -a <AppName>
+apimanager access grant -a <AppName> -p <ProxyName>
+apimanager access disable -a <AppName> -p <ProxyName>
+apimanager access enable -a <AppName> -p <ProxyName>
+apimanager access revoke -a <AppName> -p <ProxyName>`,
+	}
+	accessList = &cobra.Command{
+		Use:   "list",
+		Short: "List the proxies an application has access to",
+		Run:   listAccess,
+	}
+	accessGrant = &cobra.Command{
+		Use:   "grant",
+		Short: "Grant an application access to a proxy",
+		Run:   grantAccess,
+	}
+	accessRevoke = &cobra.Command{
+		Use:   "revoke",
+		Short: "Revoke the access of an application to a proxy",
+		Run:   revokeAccess,
+	}
+	accessEnable = &cobra.Command{
+		Use:   "enable",
+		Short: "Enable the access of an application to a proxy",
+		Run: func(cmd *cobra.Command, args []string) {
+			setAccessEnabled(true)
+		},
+	}
+	accessDisable = &cobra.Command{
+		Use:   "disable",
+		Short: "Disable the access of an application to a proxy",
+		Run: func(cmd *cobra.Command, args []string) {
+			setAccessEnabled(false)
+		},
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(accessCmd)
+	addOutputFlag(accessCmd)
+
+	accessCmd.AddCommand(accessList)
+	accessList.Flags().StringVarP(&accessApp, "app", "a", "", "application name")
+	accessList.MarkFlagRequired("app")
+
+	for _, cmd := range []*cobra.Command{accessGrant, accessRevoke, accessEnable, accessDisable} {
+		accessCmd.AddCommand(cmd)
+		cmd.Flags().StringVarP(&accessApp, "app", "a", "", "application name")
+		cmd.Flags().StringVarP(&accessProxy, "proxy", "p", "", "proxy name")
+		cmd.Flags().StringVarP(&lifecycleVersion, "proxyVersion", "v", "", "version of the proxy when several have the name")
+		cmd.MarkFlagRequired("app")
+		cmd.MarkFlagRequired("proxy")
+	}
+}
+
+func listAccess(cmd *cobra.Command, args []string) {
+	cfg := getConfig()
+	client := apimgr.NewAPIClient(cfg)
+
+	appID, err := resolveApplicationID(cfg, accessApp)
+	if err != nil {
+		utils.PrettyPrintErr("%v", err)
+		os.Exit(1)
+	}
+	access, _, err := client.ApplicationsApi.ApplicationsIdApisGet(context.Background(), appID)
+	if err != nil {
+		utils.PrettyPrintErr("Error listing the api access of %v: %v", accessApp, err)
+		os.Exit(1)
+	}
+	t, err := accessTable(cfg, access)
+	if err != nil {
+		utils.PrettyPrintErr("%v", err)
+		os.Exit(1)
+	}
+	t.printList(fmt.Sprintf("Application %v has no access to proxies", accessApp))
+}
+
+func grantAccess(cmd *cobra.Command, args []string) {
+	cfg := getConfig()
+
+	appID, proxy, access := appProxyAccess(cfg)
+	if access != nil {
+		utils.PrettyPrintInfo("Application %v already has access to Proxy %v", accessApp, proxy.Name)
+		printAccess(cfg, *access)
+		return
+	}
+	granted, err := reqApplicationAPIAccess(appID, proxy.Id, cfg)
+	if err != nil {
+		utils.PrettyPrintErr("Error granting %v access to Proxy %v: %v", accessApp, proxy.Name, err)
+		os.Exit(1)
+	}
+	utils.PrettyPrintInfo("Application %v granted access to Proxy %v", accessApp, proxy.Name)
+	printAccess(cfg, granted)
+}
+
+func revokeAccess(cmd *cobra.Command, args []string) {
+	cfg := getConfig()
+
+	appID, proxy, access := appProxyAccess(cfg)
+	if access == nil {
+		utils.PrettyPrintInfo("Application %v has no access to Proxy %v", accessApp, proxy.Name)
+		return
+	}
+	if _, err := apiRequest(cfg, "DELETE", "/applications/"+appID+"/apis/"+access.Id, nil, ""); err != nil {
+		utils.PrettyPrintErr("Error revoking the access of %v to Proxy %v: %v", accessApp, proxy.Name, err)
+		os.Exit(1)
+	}
+	utils.PrettyPrintInfo("Access of application %v to Proxy %v revoked", accessApp, proxy.Name)
+}
+
+func setAccessEnabled(enabled bool) {
+	cfg := getConfig()
+
+	state := map[bool]string{true: "enabled", false: "disabled"}[enabled]
+	appID, proxy, access := appProxyAccess(cfg)
+	if access == nil {
+		utils.PrettyPrintErr("Application %v has no access to Proxy %v, use access grant", accessApp, proxy.Name)
+		os.Exit(1)
+	}
+	if access.Enabled == enabled {
+		utils.PrettyPrintInfo("Access of application %v to Proxy %v is already %v", accessApp, proxy.Name, state)
+		printAccess(cfg, *access)
+		return
+	}
+	access.Enabled = enabled
+	body, err := json.Marshal(access)
+	if err == nil {
+		var content []byte
+		content, err = apiRequest(cfg, "PUT", "/applications/"+appID+"/apis/"+access.Id, bytes.NewReader(body), "application/json")
+		if err == nil && len(content) != 0 {
+			err = json.Unmarshal(content, access)
+		}
+	}
+	if err != nil {
+		utils.PrettyPrintErr("Error changing the access of %v to Proxy %v: %v", accessApp, proxy.Name, err)
+		os.Exit(1)
+	}
+	utils.PrettyPrintInfo("Access of application %v to Proxy %v %v", accessApp, proxy.Name, state)
+	printAccess(cfg, *access)
+}
+
+// appProxyAccess returns the application given by -a, the proxy given by -p and
+// the access of the application to the proxy, nil when it has none
+func appProxyAccess(cfg *apimgr.Configuration) (string, apimgr.VirtualizedApi, *apimgr.ApiAccess) {
+	client := apimgr.NewAPIClient(cfg)
+
+	appID, err := resolveApplicationID(cfg, accessApp)
+	if err != nil {
+		utils.PrettyPrintErr("%v", err)
+		os.Exit(1)
+	}
+	proxy, err := findProxyVersion(cfg, accessProxy, lifecycleVersion)
+	if err != nil {
+		utils.PrettyPrintErr("%v", err)
+		os.Exit(1)
+	}
+	apis, _, err := client.ApplicationsApi.ApplicationsIdApisGet(context.Background(), appID)
+	if err != nil {
+		utils.PrettyPrintErr("Error listing the api access of %v: %v", accessApp, err)
+		os.Exit(1)
+	}
+	for _, access := range apis {
+		if access.ApiId == proxy.Id {
+			return appID, proxy, &access
+		}
+	}
+	return appID, proxy, nil
+}
+
+func printAccess(cfg *apimgr.Configuration, access apimgr.ApiAccess) {
+	t, err := accessTable(cfg, []apimgr.ApiAccess{access})
+	if err != nil {
+		utils.PrettyPrintErr("%v", err)
+		return
+	}
+	t.printList("")
+}
+
+// accessTable returns the table of api access with the proxy names resolved
+func accessTable(cfg *apimgr.Configuration, access []apimgr.ApiAccess) (*resourceTable, error) {
+	client := apimgr.NewAPIClient(cfg)
+
+	proxies, _, err := client.APIProxyRegistrationApi.ProxiesGet(context.Background(), &apimgr.ProxiesGetOpts{})
+	if err != nil {
+		return nil, fmt.Errorf("error listing the proxies: %v", err)
+	}
+	byID := map[string]apimgr.VirtualizedApi{}
+	for _, proxy := range proxies {
+		byID[proxy.Id] = proxy
+	}
+
+	t := newResourceTable("access",
+		[]string{"ID", "PROXY", "VERSION", "ENABLED", "STATE", "LIFECYCLE"},
+		[]string{"PROXY ID", "CREATED BY"})
+	for _, a := range access {
+		proxy, ok := byID[a.ApiId]
+		lifecycle := proxyLifecycle(proxy)
+		if !ok {
+			proxy.Name, lifecycle = "<deleted>", ""
+		}
+		t.addRow(proxy.Name, a, a.Id, proxy.Name, proxy.Version, a.Enabled, a.State, lifecycle, a.ApiId, a.CreatedBy)
+	}
+	return t, nil
+}
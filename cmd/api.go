@@ -18,10 +18,8 @@ package cmd
 
 import (
 	"context"
-	"encoding/json"
-	"fmt"
-	"log"
 	"os"
+	"reflect"
 
 	"github.com/antihax/optional"
 	"github.com/skckadiyala/apimanager/apimgr"
@@ -78,6 +76,19 @@ For example:ß
 apimanager describe api -n <name> `,
 		Run: describeAPI,
 	}
+
+	apiEditCmd = &cobra.Command{
+		Use:   "api",
+		Short: "Edit an API",
+		Long: `Edit a backend API by name. The id, organization and creation fields
+can't be changed.
+
+For example:
+
+# Edit an api 
+apimanager edit api -n <name> `,
+		Run: editAPI,
+	}
 )
 
 func init() {
@@ -85,6 +96,7 @@ func init() {
 	listCmd.AddCommand(apiListCmd)
 	deleteCmd.AddCommand(apiDelCmd)
 	describeCmd.AddCommand(apiDescCmd)
+	editCmd.AddCommand(apiEditCmd)
 
 	apiCmd.Flags().StringVarP(&file, "swagger", "f", "", "The filename of the swagger api to be stored")
 	apiCmd.MarkFlagRequired("swagger")
@@ -98,6 +110,9 @@ func init() {
 
 	apiDescCmd.Flags().StringVarP(&apiName, "name", "n", "", "The name to store API name")
 	apiDescCmd.MarkFlagRequired("name")
+
+	apiEditCmd.Flags().StringVarP(&apiName, "name", "n", "", "The name to store API name")
+	apiEditCmd.MarkFlagRequired("name")
 }
 
 func createBackendAPI(cmd *cobra.Command, args []string) {
@@ -127,26 +142,24 @@ func listBackendAPI(cmd *cobra.Command, args []string) {
 	client := &apimgr.APIClient{}
 	client = apimgr.NewAPIClient(cfg)
 	apiGetOpts := &apimgr.ApirepoGetOpts{}
-	stdout := fmtDisplay()
 
 	apis, _, err := client.APIRepositoryApi.ApirepoGet(context.Background(), apiGetOpts)
 	if err != nil {
 		utils.PrettyPrintErr("Error Creating Backend API: %v", err)
 		return
 	}
+	backendAPITable(cfg, apis).printList("No backend api's found ")
+}
 
-	if len(apis) != 0 {
-		fmt.Fprintf(stdout, "ID\tNAME\tORGANIZATION\tBACKEND URL\tVERSION\n")
-		for _, api := range apis {
-			org, _, _ := client.OrganizationsApi.OrganizationsIdGet(context.Background(), api.OrganizationId)
-			fmt.Fprintf(stdout, "%v\t%v\t%v\t%v\t%v\n", api.Id, api.Name, org.Name, api.BasePath+api.ResourcePath, api.Version)
-		}
-		fmt.Fprint(stdout)
-		stdout.Flush()
-	} else {
-		utils.PrettyPrintInfo("No backend api's found ")
-		return
+func backendAPITable(cfg *apimgr.Configuration, apis []apimgr.Api) *resourceTable {
+	orgNameOf := organizationNames(cfg)
+	t := newResourceTable("api",
+		[]string{"ID", "NAME", "ORGANIZATION", "BACKEND URL", "VERSION"},
+		[]string{"SUMMARY", "SERVICE TYPE", "CREATED BY"})
+	for _, api := range apis {
+		t.addRow(api.Name, api, api.Id, api.Name, orgNameOf(api.OrganizationId), api.BasePath+api.ResourcePath, api.Version, api.Summary, api.ServiceType, api.CreatedBy)
 	}
+	return t
 }
 
 func getAPIByName(args []string) string {
@@ -177,6 +190,26 @@ func getAPIByName(args []string) string {
 	return apis[0].Id
 }
 
+// findBackendAPI returns the backend API with the given name, or nil when it doesn't exist
+func findBackendAPI(cfg *apimgr.Configuration, name string) (*apimgr.Api, error) {
+	client := apimgr.NewAPIClient(cfg)
+
+	apiGetOpts := &apimgr.ApirepoGetOpts{}
+
+	apiGetOpts.Field = optional.NewInterface("name")
+	apiGetOpts.Op = optional.NewInterface("eq")
+	apiGetOpts.Value = optional.NewInterface(name)
+
+	apis, _, err := client.APIRepositoryApi.ApirepoGet(context.Background(), apiGetOpts)
+	if err != nil {
+		return nil, err
+	}
+	if len(apis) == 0 {
+		return nil, nil
+	}
+	return &apis[0], nil
+}
+
 func deleteAPI(cmd *cobra.Command, args []string) {
 	cfg := getConfig()
 
@@ -206,11 +239,42 @@ func describeAPI(cmd *cobra.Command, args []string) {
 		utils.PrettyPrintErr("Unable to delete the backend API: %v", err)
 		return
 	}
+	backendAPITable(cfg, []apimgr.Api{api}).printObject()
+	return
+}
+
+func editAPI(cmd *cobra.Command, args []string) {
+	cfg := getConfig()
+	apiID := getAPIByName(args)
 
-	prettyJSON, err := json.MarshalIndent(api, "", "    ")
+	editedAPI := apimgr.Api{}
+
+	client := apimgr.NewAPIClient(cfg)
+
+	api, _, err := client.APIRepositoryApi.ApirepoIdGet(context.Background(), apiID)
 	if err != nil {
-		log.Fatal("Failed to marshal to json", err)
+		utils.PrettyPrintErr("Unable to get the backend API: %v", err)
+		return
 	}
-	fmt.Printf("%s\n", string(prettyJSON))
-	return
+	err = editObject("Backend API", api.Name, api, &editedAPI, func() error {
+		return checkImmutable("Backend API", api, editedAPI, backendAPIImmutableFields...)
+	})
+	if err == errEditCancelled {
+		utils.PrettyPrintInfo("%v", err)
+		return
+	}
+	if err != nil {
+		utils.PrettyPrintErr("%v", err)
+		return
+	}
+	if reflect.DeepEqual(api, editedAPI) {
+		utils.PrettyPrintInfo("Backend API %v has no changes to update", editedAPI.Name)
+		return
+	}
+	updatedAPI, _, err := client.APIRepositoryApi.ApirepoIdPut(context.Background(), editedAPI.Id, editedAPI)
+	if err != nil {
+		utils.PrettyPrintErr("Error updating Backend API: %v", err)
+		return
+	}
+	utils.PrettyPrintInfo("Backend API %v updated with the valid changes", updatedAPI.Name)
 }
@@ -19,6 +19,7 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/antihax/optional"
 	"github.com/skckadiyala/apimanager/apimgr"
@@ -120,8 +121,6 @@ func listAPIKeys(cmd *cobra.Command, args []string) {
 	cfg := getConfig()
 	client := &apimgr.APIClient{}
 	client = apimgr.NewAPIClient(cfg)
-	stdout := fmtDisplay()
-
 	appID := getApplicationByName(args)
 
 	keys, _, err := client.ApplicationsApi.ApplicationsIdApikeysGet(context.Background(), appID)
@@ -130,17 +129,11 @@ func listAPIKeys(cmd *cobra.Command, args []string) {
 		utils.PrettyPrintErr("Error listing the apiKeys: %v", err)
 		return
 	}
-	if len(keys) != 0 {
-		fmt.Fprintf(stdout, "APIKEY\tSECRET\n")
-		for _, key := range keys {
-			fmt.Fprintf(stdout, "%v\t%v\n", key.Id, key.Secret)
-		}
-		fmt.Fprint(stdout)
-		stdout.Flush()
-	} else {
-		utils.PrettyPrintInfo("No apikeys found in the application %v", appName)
-		return
+	t := newResourceTable("key", []string{"APIKEY", "SECRET"}, []string{"ENABLED", "CORS ORIGINS", "CREATED BY"})
+	for _, key := range keys {
+		t.addRow(key.Id, key, key.Id, key.Secret, key.Enabled, strings.Join(key.CorsOrigins, ","), key.CreatedBy)
 	}
+	t.printList(fmt.Sprintf("No apikeys found in the application %v", appName))
 }
 
 func deleteAPIKey(cmd *cobra.Command, args []string) {
@@ -21,7 +21,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
-	"log"
 	"os"
 	"reflect"
 
@@ -192,11 +191,10 @@ func deleteApplication(cmd *cobra.Command, args []string) {
 func describeApplication(cmd *cobra.Command, args []string) {
 
 	app, err := descApplication(cmd, args)
-	prettyJSON, err := json.MarshalIndent(app, "", "    ")
 	if err != nil {
-		log.Fatal("Failed to marshal to json", err)
+		return
 	}
-	fmt.Printf("%s\n", string(prettyJSON))
+	applicationTable(getConfig(), []apimgr.Application{app}).printObject()
 	return
 }
 
@@ -205,7 +203,6 @@ func editApplication(cmd *cobra.Command, args []string) {
 
 	editedApp := apimgr.Application{}
 
-	fname := getUniqueID(5)
 	client := &apimgr.APIClient{}
 	client = apimgr.NewAPIClient(cfg)
 
@@ -213,43 +210,30 @@ func editApplication(cmd *cobra.Command, args []string) {
 	if err != nil {
 		return
 	}
-	prettyJSON, err := json.MarshalIndent(app, "", "    ")
-	if err != nil {
-		log.Fatal("Failed to marshal org to json", err)
+	err = editObject("Application", app.Name, app, &editedApp, func() error {
+		return checkImmutable("Application", app, editedApp, applicationImmutableFields...)
+	})
+	if err == errEditCancelled {
+		utils.PrettyPrintInfo("%v", err)
 		return
 	}
-
-	err = createTempFile(fname, prettyJSON)
 	if err != nil {
-		return
-	}
-
-	editedObj, _ := ioutil.ReadFile("/tmp/" + fname)
-	err = json.Unmarshal(editedObj, &editedApp)
-	if err != nil {
-		fmt.Println("Invalid json object, no changes applied")
+		utils.PrettyPrintErr("%v", err)
 		return
 	}
 	appVars := &apimgr.ApplicationsIdPutOpts{}
 	appVars.Body = optional.NewInterface(editedApp)
 
-	if editedApp.Id != app.Id || editedApp.CreatedOn != app.CreatedOn {
-		fmt.Println("Application can't be updated with the changed values")
-		return
-	}
-	// fmt.Println(app)
-	// fmt.Println(editedApp)
 	if reflect.DeepEqual(app, editedApp) {
 		utils.PrettyPrintInfo("Application %v has no changes to update", editedApp.Name)
 		return
 	}
 	updatedApp, _, err := client.ApplicationsApi.ApplicationsIdPut(context.Background(), editedApp.Id, appVars)
 	if err != nil {
-		utils.PrettyPrintErr("Error updating Application")
+		utils.PrettyPrintErr("Error updating Application: %v", err)
 		return
 	}
 	utils.PrettyPrintInfo("Application %v updated with the valid changes", updatedApp.Name)
-	deleteTempFile(fname)
 	return
 
 }
@@ -282,6 +266,26 @@ func getApplicationByName(args []string) string {
 	return apps[0].Id
 }
 
+// findApplication returns the application with the given name, or nil when it doesn't exist
+func findApplication(cfg *apimgr.Configuration, name string) (*apimgr.Application, error) {
+	client := apimgr.NewAPIClient(cfg)
+
+	getAppVars := &apimgr.ApplicationsGetOpts{}
+
+	getAppVars.Field = optional.NewInterface("name")
+	getAppVars.Op = optional.NewInterface("eq")
+	getAppVars.Value = optional.NewInterface(name)
+
+	apps, _, err := client.ApplicationsApi.ApplicationsGet(context.Background(), getAppVars)
+	if err != nil {
+		return nil, err
+	}
+	if len(apps) == 0 {
+		return nil, nil
+	}
+	return &apps[0], nil
+}
+
 func listApplications(cmd *cobra.Command, args []string) {
 	cfg := getConfig()
 	client := &apimgr.APIClient{}
@@ -289,33 +293,28 @@ func listApplications(cmd *cobra.Command, args []string) {
 
 	getAppVars := &apimgr.ApplicationsGetOpts{}
 
-	stdout := fmtDisplay()
-
 	apps, _, err := client.ApplicationsApi.ApplicationsGet(context.Background(), getAppVars)
 	if err != nil {
 		utils.PrettyPrintErr("Error listing the applications: %v", err)
 		return
 	}
-	if len(apps) != 0 {
-		// fmt.Printf("Name \t\t ID \n")
-		fmt.Fprintf(stdout, "ID\tNAME\tDESCRIPTION\tORGANIZATION\n")
-		for _, app := range apps {
-			// fmt.Printf("%v \t %v \n", app.Name, app.Id)
-			org, _, _ := client.OrganizationsApi.OrganizationsIdGet(context.Background(), app.OrganizationId)
-			fmt.Fprintf(stdout, "%v\t%v\t%vv\t%v\n", app.Id, app.Name, app.Description, org.Name)
-		}
-		fmt.Fprint(stdout)
-		stdout.Flush()
-	} else {
-		utils.PrettyPrintInfo("No application found ")
-		return
-	}
+	applicationTable(cfg, apps).printList("No application found ")
 }
 
-func reqApplicationAPIAccess(appID, apiID string, cfg *apimgr.Configuration) {
+func applicationTable(cfg *apimgr.Configuration, apps []apimgr.Application) *resourceTable {
+	orgNameOf := organizationNames(cfg)
+	t := newResourceTable("application",
+		[]string{"ID", "NAME", "DESCRIPTION", "ORGANIZATION"},
+		[]string{"EMAIL", "ENABLED", "STATE", "CREATED BY"})
+	for _, app := range apps {
+		t.addRow(app.Name, app, app.Id, app.Name, app.Description, orgNameOf(app.OrganizationId), app.Email, app.Enabled, app.State, app.CreatedBy)
+	}
+	return t
+}
 
-	client := &apimgr.APIClient{}
-	client = apimgr.NewAPIClient(cfg)
+// reqApplicationAPIAccess grants the application access to the api
+func reqApplicationAPIAccess(appID, apiID string, cfg *apimgr.Configuration) (apimgr.ApiAccess, error) {
+	client := apimgr.NewAPIClient(cfg)
 
 	reqBody := apimgr.ApiAccess{}
 	reqBody.ApiId = apiID
@@ -325,11 +324,44 @@ func reqApplicationAPIAccess(appID, apiID string, cfg *apimgr.Configuration) {
 	optVars.Body = optional.NewInterface(reqBody)
 
 	apiAccess, _, err := client.ApplicationsApi.ApplicationsIdApisPost(context.Background(), appID, optVars)
+	return apiAccess, err
+}
+
+// hasApplicationAPIAccess reports whether the application was already granted access to the api
+func hasApplicationAPIAccess(appID, apiID string, cfg *apimgr.Configuration) (bool, error) {
+	client := apimgr.NewAPIClient(cfg)
+
+	apis, _, err := client.ApplicationsApi.ApplicationsIdApisGet(context.Background(), appID)
 	if err != nil {
-		utils.PrettyPrintErr("Error creating access to apis %v", err)
+		return false, err
+	}
+	for _, api := range apis {
+		if api.ApiId == apiID {
+			return true, nil
+		}
 	}
+	return false, nil
+}
+
+// getProxyApplications returns the applications that were granted access to the proxy
+func getProxyApplications(cfg *apimgr.Configuration, proxyID string) ([]apimgr.Application, error) {
+	client := apimgr.NewAPIClient(cfg)
 
-	utils.PrettyPrintInfo(apiAccess.ApiId)
+	apps, _, err := client.ApplicationsApi.ApplicationsGet(context.Background(), &apimgr.ApplicationsGetOpts{})
+	if err != nil {
+		return nil, err
+	}
+	granted := []apimgr.Application{}
+	for _, app := range apps {
+		ok, err := hasApplicationAPIAccess(app.Id, proxyID, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list the api access of %v: %v", app.Name, err)
+		}
+		if ok {
+			granted = append(granted, app)
+		}
+	}
+	return granted, nil
 }
 
 func descApplication(cmd *cobra.Command, args []string) (apimgr.Application, error) {
@@ -0,0 +1,780 @@
+/*
+Copyright © 2020 Axway, Inc. <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/antihax/optional"
+	"github.com/skckadiyala/apimanager/apimgr"
+	"github.com/skckadiyala/kubecrt-vms/utils"
+	"github.com/spf13/cobra"
+)
+
+var (
+	manifestPaths []string
+	recursive     bool
+)
+
+// applyCmd represents the apply command
+var applyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "Apply a configuration to API Manager resources from files",
+	Long: `Apply a configuration to API Manager resources from files or directories.
+YAML and JSON formats are accepted, a file may hold several documents separated by '---'.
+Resources are created when they don't exist and updated otherwise. Resources are applied
+in dependency order: organizations first, then users, applications and backend APIs, then
+proxies, API keys and OAuth clients.
+
+For example:
+
+  # Apply the resources in marvel.yaml
+  apimanager apply -f marvel.yaml
+
+  # Apply all manifests in a directory and its sub directories
+  apimanager apply -f ./manifests -R
+
+A manifest names the resource and refers to other resources by name:
+
+  kind: Proxy
+  name: The Winter Soldier
+  organization: Marvel
+  backendAPI: Captain America
+  security: apikey
+  certs: [cert.pem]
+  applications: [Avengers]
+  spec:
+    path: /api/winter/v1
+    version: "1.0"
+    state: published
+`,
+	Run: apply,
+}
+
+func init() {
+	rootCmd.AddCommand(applyCmd)
+
+	applyCmd.Flags().StringSliceVarP(&manifestPaths, "file", "f", []string{}, "manifest files or directories to apply")
+	applyCmd.MarkFlagRequired("file")
+	applyCmd.Flags().BoolVarP(&recursive, "recursive", "R", false, "process the directories used in -f recursively")
+}
+
+func apply(cmd *cobra.Command, args []string) {
+	manifests, err := loadManifests(manifestPaths, recursive)
+	if err != nil {
+		utils.PrettyPrintErr("Error reading manifests: %v", err)
+		os.Exit(1)
+	}
+	sortManifests(manifests)
+
+	cfg := getConfig()
+	failed := 0
+	for _, m := range manifests {
+		action, err := applyManifest(cfg, m)
+		if err != nil {
+			utils.PrettyPrintErr("%v failed: %v", m, err)
+			failed++
+			continue
+		}
+		utils.PrettyPrintInfo("%v %v", m, action)
+	}
+	if failed != 0 {
+		os.Exit(1)
+	}
+}
+
+// applyManifest creates or updates the resource described by the manifest and
+// returns the action taken
+func applyManifest(cfg *apimgr.Configuration, m manifest) (string, error) {
+	switch m.Kind {
+	case kindOrganization:
+		return applyOrganization(cfg, m)
+	case kindUser:
+		return applyUser(cfg, m)
+	case kindApplication:
+		return applyApplication(cfg, m)
+	case kindBackendAPI:
+		return applyBackendAPI(cfg, m)
+	case kindProxy:
+		return applyProxy(cfg, m)
+	case kindAPIKey:
+		return applyAPIKey(cfg, m)
+	case kindOAuthClient:
+		return applyOAuthClient(cfg, m)
+	}
+	return "", fmt.Errorf("unknown kind %q", m.Kind)
+}
+
+// decodeSpec overlays the manifest spec on obj
+func (m manifest) decodeSpec(obj interface{}) error {
+	if len(m.Spec) == 0 {
+		return nil
+	}
+	return json.Unmarshal(m.Spec, obj)
+}
+
+// plannedResources holds the resources of the manifests diff compares, references
+// to those that don't exist yet resolve to a placeholder id instead of failing
+var plannedResources map[string]bool
+
+func plannedKey(kind, name string) string {
+	return kind + "/" + name
+}
+
+// plannedID returns the placeholder id of a resource the manifests create
+func plannedID(kind, name string) string {
+	return "<new " + kind + " " + name + ">"
+}
+
+func isPlannedID(id string) bool {
+	return strings.HasPrefix(id, "<new ")
+}
+
+func resolveOrganizationID(cfg *apimgr.Configuration, name string) (string, error) {
+	org, err := findOrganization(cfg, name)
+	if err != nil {
+		return "", err
+	}
+	if org == nil {
+		if plannedResources[plannedKey(kindOrganization, name)] {
+			return plannedID(kindOrganization, name), nil
+		}
+		return "", fmt.Errorf("organization %v not found", name)
+	}
+	return org.Id, nil
+}
+
+func resolveApplicationID(cfg *apimgr.Configuration, name string) (string, error) {
+	app, err := findApplication(cfg, name)
+	if err != nil {
+		return "", err
+	}
+	if app == nil {
+		if plannedResources[plannedKey(kindApplication, name)] {
+			return plannedID(kindApplication, name), nil
+		}
+		return "", fmt.Errorf("application %v not found", name)
+	}
+	return app.Id, nil
+}
+
+func resolveBackendAPIID(cfg *apimgr.Configuration, name string) (string, error) {
+	api, err := findBackendAPI(cfg, name)
+	if err != nil {
+		return "", err
+	}
+	if api == nil {
+		if plannedResources[plannedKey(kindBackendAPI, name)] {
+			return plannedID(kindBackendAPI, name), nil
+		}
+		return "", fmt.Errorf("backend API %v not found", name)
+	}
+	return api.Id, nil
+}
+
+// desiredOrganization returns the organization the manifest describes, live is nil when it doesn't exist yet
+func desiredOrganization(cfg *apimgr.Configuration, m manifest, live *apimgr.Organization) (apimgr.Organization, error) {
+	org := apimgr.Organization{}
+	if live != nil {
+		org = *live
+	}
+	if err := m.decodeSpec(&org); err != nil {
+		return org, err
+	}
+	org.Name = m.Name
+	if live != nil {
+		org.Id, org.Dn, org.CreatedOn = live.Id, live.Dn, live.CreatedOn
+	} else {
+		org.Id, org.Dn, org.CreatedOn = "", "", 0
+	}
+	return org, nil
+}
+
+func applyOrganization(cfg *apimgr.Configuration, m manifest) (string, error) {
+	client := apimgr.NewAPIClient(cfg)
+
+	live, err := findOrganization(cfg, m.Name)
+	if err != nil {
+		return "", err
+	}
+	org, err := desiredOrganization(cfg, m, live)
+	if err != nil {
+		return "", err
+	}
+	if live == nil {
+		orgVars := &apimgr.OrganizationsPostOpts{}
+		orgVars.Body = optional.NewInterface(org)
+		_, _, err = client.OrganizationsApi.OrganizationsPost(context.Background(), orgVars)
+		if err != nil {
+			return "", err
+		}
+		return "created", nil
+	}
+	if reflect.DeepEqual(*live, org) {
+		return "unchanged", nil
+	}
+	orgVars := &apimgr.OrganizationsIdPutOpts{}
+	orgVars.Body = optional.NewInterface(org)
+	_, _, err = client.OrganizationsApi.OrganizationsIdPut(context.Background(), org.Id, orgVars)
+	if err != nil {
+		return "", err
+	}
+	return "configured", nil
+}
+
+// desiredUser returns the user the manifest describes, live is nil when it doesn't exist yet
+func desiredUser(cfg *apimgr.Configuration, m manifest, live *apimgr.User) (apimgr.User, error) {
+	user := apimgr.User{}
+	if live != nil {
+		user = *live
+	}
+	if err := m.decodeSpec(&user); err != nil {
+		return user, err
+	}
+	user.Name = m.Name
+	if live != nil {
+		user.Id, user.Dn, user.CreatedOn = live.Id, live.Dn, live.CreatedOn
+	} else {
+		user.Id, user.Dn, user.CreatedOn = "", "", 0
+	}
+	if m.Organization != "" {
+		orgID, err := resolveOrganizationID(cfg, m.Organization)
+		if err != nil {
+			return user, err
+		}
+		user.OrganizationId = orgID
+	} else if live == nil {
+		return user, fmt.Errorf("organization is required")
+	}
+	return user, nil
+}
+
+func applyUser(cfg *apimgr.Configuration, m manifest) (string, error) {
+	client := apimgr.NewAPIClient(cfg)
+
+	live, err := findUser(cfg, m.Name)
+	if err != nil {
+		return "", err
+	}
+	user, err := desiredUser(cfg, m, live)
+	if err != nil {
+		return "", err
+	}
+	if live == nil {
+		userVars := &apimgr.UsersPostOpts{}
+		userVars.Body = optional.NewInterface(user)
+		user, _, err = client.UsersApi.UsersPost(context.Background(), userVars)
+		if err != nil {
+			return "", err
+		}
+		if m.Password != "" {
+			_, err = client.UsersApi.UsersIdChangepasswordPost(context.Background(), user.Id, m.Password)
+			if err != nil {
+				return "", fmt.Errorf("created but the password was not set: %v", err)
+			}
+		}
+		return "created", nil
+	}
+	if reflect.DeepEqual(*live, user) {
+		return "unchanged", nil
+	}
+	userVars := &apimgr.UsersIdPutOpts{}
+	userVars.Body = optional.NewInterface(user)
+	_, _, err = client.UsersApi.UsersIdPut(context.Background(), user.Id, userVars)
+	if err != nil {
+		return "", err
+	}
+	return "configured", nil
+}
+
+// desiredApplication returns the application the manifest describes, live is nil when it doesn't exist yet
+func desiredApplication(cfg *apimgr.Configuration, m manifest, live *apimgr.Application) (apimgr.Application, error) {
+	app := apimgr.Application{}
+	if live != nil {
+		app = *live
+	}
+	if err := m.decodeSpec(&app); err != nil {
+		return app, err
+	}
+	app.Name = m.Name
+	if live != nil {
+		app.Id, app.CreatedOn, app.CreatedBy = live.Id, live.CreatedOn, live.CreatedBy
+	} else {
+		app.Id, app.CreatedOn, app.CreatedBy = "", 0, ""
+	}
+	if m.Organization != "" {
+		orgID, err := resolveOrganizationID(cfg, m.Organization)
+		if err != nil {
+			return app, err
+		}
+		app.OrganizationId = orgID
+	} else if live == nil {
+		return app, fmt.Errorf("organization is required")
+	}
+	return app, nil
+}
+
+func applyApplication(cfg *apimgr.Configuration, m manifest) (string, error) {
+	client := apimgr.NewAPIClient(cfg)
+
+	live, err := findApplication(cfg, m.Name)
+	if err != nil {
+		return "", err
+	}
+	app, err := desiredApplication(cfg, m, live)
+	if err != nil {
+		return "", err
+	}
+	if live == nil {
+		appReq := apimgr.ApplicationRequest{}
+		body, err := json.Marshal(app)
+		if err != nil {
+			return "", err
+		}
+		if err = json.Unmarshal(body, &appReq); err != nil {
+			return "", err
+		}
+		appVars := &apimgr.ApplicationsPostOpts{}
+		appVars.Body = optional.NewInterface(appReq)
+		_, _, err = client.ApplicationsApi.ApplicationsPost(context.Background(), appVars)
+		if err != nil {
+			return "", err
+		}
+		return "created", nil
+	}
+	if reflect.DeepEqual(*live, app) {
+		return "unchanged", nil
+	}
+	appVars := &apimgr.ApplicationsIdPutOpts{}
+	appVars.Body = optional.NewInterface(app)
+	_, _, err = client.ApplicationsApi.ApplicationsIdPut(context.Background(), app.Id, appVars)
+	if err != nil {
+		return "", err
+	}
+	return "configured", nil
+}
+
+// desiredBackendAPI returns the backend API the manifest describes, live is nil when it doesn't exist yet
+func desiredBackendAPI(cfg *apimgr.Configuration, m manifest, live *apimgr.Api) (apimgr.Api, error) {
+	api := apimgr.Api{}
+	if live != nil {
+		api = *live
+	}
+	if err := m.decodeSpec(&api); err != nil {
+		return api, err
+	}
+	api.Name = m.Name
+	if live != nil {
+		api.Id, api.CreatedOn, api.CreatedBy = live.Id, live.CreatedOn, live.CreatedBy
+	}
+	if m.Organization != "" {
+		orgID, err := resolveOrganizationID(cfg, m.Organization)
+		if err != nil {
+			return api, err
+		}
+		api.OrganizationId = orgID
+	} else if live == nil {
+		return api, fmt.Errorf("organization is required")
+	}
+	return api, nil
+}
+
+func applyBackendAPI(cfg *apimgr.Configuration, m manifest) (string, error) {
+	client := apimgr.NewAPIClient(cfg)
+
+	live, err := findBackendAPI(cfg, m.Name)
+	if err != nil {
+		return "", err
+	}
+	api, err := desiredBackendAPI(cfg, m, live)
+	if err != nil {
+		return "", err
+	}
+	if live == nil {
+		if m.Swagger == "" {
+			return "", fmt.Errorf("swagger is required to import a backend API")
+		}
+		swagger, err := os.Open(m.path(m.Swagger))
+		if err != nil {
+			return "", err
+		}
+		defer swagger.Close()
+		_, _, err = client.APIRepositoryApi.ApirepoImportPost(context.Background(), api.OrganizationId, api.Name, "swagger", swagger)
+		if err != nil {
+			return "", err
+		}
+		return "created", nil
+	}
+	// the swagger is only imported once, later applies update the API details
+	if reflect.DeepEqual(*live, api) {
+		return "unchanged", nil
+	}
+	_, _, err = client.APIRepositoryApi.ApirepoIdPut(context.Background(), api.Id, api)
+	if err != nil {
+		return "", err
+	}
+	return "configured", nil
+}
+
+// desiredProxy returns the proxy the manifest describes, live is nil when it doesn't exist yet
+func desiredProxy(cfg *apimgr.Configuration, m manifest, live *apimgr.VirtualizedApi) (apimgr.VirtualizedApi, error) {
+	proxy := apimgr.VirtualizedApi{}
+	if live != nil {
+		proxy = *live
+	}
+	if err := m.decodeSpec(&proxy); err != nil {
+		return proxy, err
+	}
+	proxy.Name = m.Name
+	if live != nil {
+		proxy.Id, proxy.CreatedOn, proxy.CreatedBy = live.Id, live.CreatedOn, live.CreatedBy
+	} else {
+		proxy.Id, proxy.CreatedOn, proxy.CreatedBy = "", 0, ""
+	}
+	if m.Organization != "" {
+		orgID, err := resolveOrganizationID(cfg, m.Organization)
+		if err != nil {
+			return proxy, err
+		}
+		proxy.OrganizationId = orgID
+	} else if live == nil {
+		return proxy, fmt.Errorf("organization is required")
+	}
+	if m.BackendAPI != "" {
+		apiID, err := resolveBackendAPIID(cfg, m.BackendAPI)
+		if err != nil {
+			return proxy, err
+		}
+		proxy.ApiId = apiID
+	} else if live == nil {
+		return proxy, fmt.Errorf("backendAPI is required")
+	}
+	if m.Security != "" {
+		profiles, err := getSecurityProfiles(m.Security)
+		if err != nil {
+			return proxy, err
+		}
+		proxy.SecurityProfiles = profiles
+	} else if live == nil && len(proxy.SecurityProfiles) == 0 {
+		proxy.SecurityProfiles = getSecurityProfilePassThrough()
+	}
+	if m.BackendAuth != nil {
+		profile, err := m.BackendAuth.authenticationProfile(m.path)
+		if err != nil {
+			return proxy, err
+		}
+		setBackendAuth(&proxy, profile)
+	}
+	if m.CorsProfile != "" || m.Cors != nil {
+		profile, err := m.corsProfile()
+		if err != nil {
+			return proxy, err
+		}
+		setProxyCors(&proxy, profile)
+	}
+	if len(m.Certs) != 0 {
+		proxy.CaCerts = []apimgr.CaCert{}
+		for _, cert := range m.Certs {
+			certs, err := importCerts(cfg, m.path(cert))
+			if err != nil {
+				return proxy, err
+			}
+			proxy.CaCerts = append(proxy.CaCerts, certs...)
+		}
+	}
+	if live != nil && len(m.Methods) != 0 {
+		if err := setMethodOverrides(cfg, &proxy, m.Methods, m.path); err != nil {
+			return proxy, err
+		}
+	}
+	return proxy, nil
+}
+
+func applyProxy(cfg *apimgr.Configuration, m manifest) (string, error) {
+	client := apimgr.NewAPIClient(cfg)
+
+	live, err := findProxy(cfg, m.Name)
+	if err != nil {
+		return "", err
+	}
+	proxy, err := desiredProxy(cfg, m, live)
+	if err != nil {
+		return "", err
+	}
+	action := "unchanged"
+	if live == nil {
+		if proxy.Path == "" {
+			proxy.Path = "/api/" + getUniqueID(5) + "/v1"
+		}
+		proxy, _, err = client.APIProxyRegistrationApi.ProxiesPost(context.Background(), proxy)
+		if err != nil {
+			return "", err
+		}
+		action = "created"
+		if len(m.Methods) != 0 {
+			// the method ids exist once the proxy is created
+			created := proxy
+			if err = setMethodOverrides(cfg, &proxy, m.Methods, m.path); err != nil {
+				return "", err
+			}
+			if proxy, err = putProxy(cfg, created, proxy); err != nil {
+				return "", err
+			}
+		}
+	} else {
+		// state changes go through publish and unpublish
+		state := proxy.State
+		proxy.State = live.State
+		if !reflect.DeepEqual(*live, proxy) {
+			proxy, err = putProxy(cfg, *live, proxy)
+			if err != nil {
+				return "", err
+			}
+			action = "configured"
+		}
+		if state != "" && state != live.State {
+			if err = setProxyState(cfg, proxy, state); err != nil {
+				return "", err
+			}
+			action = "configured"
+		}
+	}
+
+	for _, name := range m.Applications {
+		appID, err := resolveApplicationID(cfg, name)
+		if err != nil {
+			return "", err
+		}
+		granted, err := hasApplicationAPIAccess(appID, proxy.Id, cfg)
+		if err != nil {
+			return "", err
+		}
+		if granted {
+			continue
+		}
+		if _, err = reqApplicationAPIAccess(appID, proxy.Id, cfg); err != nil {
+			return "", fmt.Errorf("unable to grant access to %v: %v", name, err)
+		}
+		if action == "unchanged" {
+			action = "configured"
+		}
+	}
+	return action, nil
+}
+
+// APIKey and OAuthClient manifests are named after the key id. An unnamed manifest is
+// the first key of the application with the values of its spec, so without a spec any
+// key satisfies it.
+
+// claimedKeys are the keys matched or created by the manifests handled so far, an
+// unnamed manifest doesn't match the key of another manifest
+var claimedKeys = map[string]bool{}
+
+// desiredAPIKey returns the API key the manifest describes, live is nil when it doesn't exist yet
+func desiredAPIKey(m manifest, appID string, live *apimgr.ApiKey) (apimgr.ApiKey, error) {
+	apikey := apimgr.ApiKey{}
+	if live != nil {
+		apikey = *live
+	}
+	if err := m.decodeSpec(&apikey); err != nil {
+		return apikey, err
+	}
+	apikey.ApplicationId = appID
+	if live != nil {
+		apikey.Id, apikey.CreatedOn, apikey.CreatedBy = live.Id, live.CreatedOn, live.CreatedBy
+	} else {
+		apikey.Id, apikey.CreatedOn, apikey.CreatedBy = m.Name, 0, ""
+	}
+	return apikey, nil
+}
+
+// findAPIKey returns the API key of the application the manifest describes, nil when
+// it doesn't exist
+func findAPIKey(cfg *apimgr.Configuration, m manifest, appID string) (*apimgr.ApiKey, error) {
+	client := apimgr.NewAPIClient(cfg)
+
+	keys, _, err := client.ApplicationsApi.ApplicationsIdApikeysGet(context.Background(), appID)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		if m.Name != "" {
+			if key.Id == m.Name {
+				return &key, nil
+			}
+			continue
+		}
+		if claimedKeys[key.Id] {
+			continue
+		}
+		desired, err := desiredAPIKey(m, appID, &key)
+		if err != nil {
+			return nil, err
+		}
+		if reflect.DeepEqual(desired, key) {
+			return &key, nil
+		}
+	}
+	return nil, nil
+}
+
+func applyAPIKey(cfg *apimgr.Configuration, m manifest) (string, error) {
+	client := apimgr.NewAPIClient(cfg)
+
+	if m.Application == "" {
+		return "", fmt.Errorf("application is required")
+	}
+	appID, err := resolveApplicationID(cfg, m.Application)
+	if err != nil {
+		return "", err
+	}
+	live, err := findAPIKey(cfg, m, appID)
+	if err != nil {
+		return "", err
+	}
+	apikey, err := desiredAPIKey(m, appID, live)
+	if err != nil {
+		return "", err
+	}
+	if live == nil {
+		apikeyPost := &apimgr.ApplicationsIdApikeysPostOpts{}
+		apikeyPost.ApiKey = optional.NewInterface(apikey)
+		created, _, err := client.ApplicationsApi.ApplicationsIdApikeysPost(context.Background(), appID, apikeyPost)
+		if err != nil {
+			return "", err
+		}
+		claimedKeys[created.Id] = true
+		return "created", nil
+	}
+	claimedKeys[live.Id] = true
+	if reflect.DeepEqual(*live, apikey) {
+		return "unchanged", nil
+	}
+	body, err := json.Marshal(apikey)
+	if err != nil {
+		return "", err
+	}
+	if _, err = apiRequest(cfg, "PUT", "/applications/"+appID+"/apikeys/"+apikey.Id, bytes.NewReader(body), "application/json"); err != nil {
+		return "", err
+	}
+	return "configured", nil
+}
+
+// desiredOAuthClient returns the OAuth client the manifest describes, live is nil when it doesn't exist yet
+func desiredOAuthClient(m manifest, appID string, live *apimgr.OAuthClient) (apimgr.OAuthClient, error) {
+	oauth := apimgr.OAuthClient{}
+	if live != nil {
+		oauth = *live
+	}
+	if err := m.decodeSpec(&oauth); err != nil {
+		return oauth, err
+	}
+	oauth.ApplicationId = appID
+	if live != nil {
+		oauth.Id, oauth.CreatedOn, oauth.CreatedBy = live.Id, live.CreatedOn, live.CreatedBy
+	} else {
+		oauth.Id, oauth.CreatedOn, oauth.CreatedBy = m.Name, 0, ""
+		if oauth.Type == "" {
+			oauth.Type = "public"
+		}
+	}
+	if m.Cert != "" {
+		certContent, err := ioutil.ReadFile(m.path(m.Cert))
+		if err != nil {
+			return oauth, err
+		}
+		oauth.Cert = string(certContent)
+	}
+	return oauth, nil
+}
+
+// findOAuthClient returns the OAuth client of the application the manifest describes,
+// nil when it doesn't exist
+func findOAuthClient(cfg *apimgr.Configuration, m manifest, appID string) (*apimgr.OAuthClient, error) {
+	client := apimgr.NewAPIClient(cfg)
+
+	oauths, _, err := client.ApplicationsApi.ApplicationsIdOauthGet(context.Background(), appID)
+	if err != nil {
+		return nil, err
+	}
+	for _, oauth := range oauths {
+		if m.Name != "" {
+			if oauth.Id == m.Name {
+				return &oauth, nil
+			}
+			continue
+		}
+		if claimedKeys[oauth.Id] {
+			continue
+		}
+		desired, err := desiredOAuthClient(m, appID, &oauth)
+		if err != nil {
+			return nil, err
+		}
+		if reflect.DeepEqual(desired, oauth) {
+			return &oauth, nil
+		}
+	}
+	return nil, nil
+}
+
+func applyOAuthClient(cfg *apimgr.Configuration, m manifest) (string, error) {
+	client := apimgr.NewAPIClient(cfg)
+
+	if m.Application == "" {
+		return "", fmt.Errorf("application is required")
+	}
+	appID, err := resolveApplicationID(cfg, m.Application)
+	if err != nil {
+		return "", err
+	}
+	live, err := findOAuthClient(cfg, m, appID)
+	if err != nil {
+		return "", err
+	}
+	oauth, err := desiredOAuthClient(m, appID, live)
+	if err != nil {
+		return "", err
+	}
+	if live == nil {
+		created, _, err := client.ApplicationsApi.ApplicationsIdOauthPost(context.Background(), appID, oauth)
+		if err != nil {
+			return "", err
+		}
+		claimedKeys[created.Id] = true
+		return "created", nil
+	}
+	claimedKeys[live.Id] = true
+	if reflect.DeepEqual(*live, oauth) {
+		return "unchanged", nil
+	}
+	body, err := json.Marshal(oauth)
+	if err != nil {
+		return "", err
+	}
+	if _, err = apiRequest(cfg, "PUT", "/applications/"+appID+"/oauth/"+oauth.Id, bytes.NewReader(body), "application/json"); err != nil {
+		return "", err
+	}
+	return "configured", nil
+}
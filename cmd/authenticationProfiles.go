@@ -0,0 +1,219 @@
+/*
+Copyright © 2020 Axway, Inc. <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/skckadiyala/apimanager/apimgr"
+	"github.com/spf13/cobra"
+)
+
+// backendAuth describes how the gateway authenticates to the backend API of a proxy,
+// it is set with the --backend-* flags or the backendAuth field of a manifest
+type backendAuth struct {
+	Type          string `json:"type"` // none, basic, apikey, oauth or ssl
+	Username      string `json:"username,omitempty"`
+	Password      string `json:"password,omitempty"`
+	APIKey        string `json:"apiKey,omitempty"`
+	APIKeyField   string `json:"apiKeyField,omitempty"`
+	TakeFrom      string `json:"takeFrom,omitempty"` // HEADER or QUERY
+	OAuthProvider string `json:"oauthProvider,omitempty"`
+	OAuthOwner    string `json:"oauthOwner,omitempty"`
+	Cert          string `json:"cert,omitempty"` // PKCS#12 file
+	CertPassword  string `json:"certPassword,omitempty"`
+	// TrustAll turns off the check of the backend server certificate with ssl
+	TrustAll bool `json:"trustAll,omitempty"`
+}
+
+var backendAuthFlags backendAuth
+
+// backendSecretFiles are the files the backend secrets are read from, - is stdin
+var backendSecretFiles struct {
+	password     string
+	apiKey       string
+	certPassword string
+}
+
+// the environment variables holding the backend secrets when neither the value nor
+// the file flag is given, so they don't show in ps or the shell history
+const (
+	backendPasswordEnv     = "APIMANAGER_BACKEND_PASSWORD"
+	backendAPIKeyEnv       = "APIMANAGER_BACKEND_API_KEY"
+	backendCertPasswordEnv = "APIMANAGER_BACKEND_CERT_PASSWORD"
+)
+
+// addBackendAuthFlags adds the outbound authentication flags of create proxy and update proxy
+func addBackendAuthFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&backendAuthFlags.Type, "backend-auth", "", "authentication to the backend API: \nnone \nbasic \napikey \noauth \nssl")
+	cmd.Flags().StringVar(&backendAuthFlags.Username, "backend-username", "", "username of the basic backend authentication")
+	cmd.Flags().StringVar(&backendAuthFlags.Password, "backend-password", "", "password of the basic backend authentication, defaults to $"+backendPasswordEnv)
+	cmd.Flags().StringVar(&backendSecretFiles.password, "backend-password-file", "", "file with the password of the basic backend authentication, - for stdin")
+	cmd.Flags().StringVar(&backendAuthFlags.APIKey, "backend-api-key", "", "API key sent to the backend, defaults to $"+backendAPIKeyEnv)
+	cmd.Flags().StringVar(&backendSecretFiles.apiKey, "backend-api-key-file", "", "file with the API key sent to the backend, - for stdin")
+	cmd.Flags().StringVar(&backendAuthFlags.APIKeyField, "backend-api-key-field", "KeyId", "header or query parameter the API key is sent in")
+	cmd.Flags().StringVar(&backendAuthFlags.TakeFrom, "backend-api-key-in", "HEADER", "where the API key is sent: HEADER or QUERY")
+	cmd.Flags().StringVar(&backendAuthFlags.OAuthProvider, "backend-oauth-provider", "", "OAuth client provider profile that gets the backend token, a name or an entity store key")
+	cmd.Flags().StringVar(&backendAuthFlags.OAuthOwner, "backend-oauth-owner", "${authentication.subject.id}", "owner of the backend OAuth token")
+	cmd.Flags().StringVar(&backendAuthFlags.Cert, "backend-cert", "", "PKCS#12 file with the client certificate presented to the backend")
+	cmd.Flags().StringVar(&backendAuthFlags.CertPassword, "backend-cert-password", "", "password of the backend client certificate file, defaults to $"+backendCertPasswordEnv)
+	cmd.Flags().StringVar(&backendSecretFiles.certPassword, "backend-cert-password-file", "", "file with the password of the backend client certificate file, - for stdin")
+	cmd.Flags().BoolVar(&backendAuthFlags.TrustAll, "backend-trust-all", false, "don't check the certificate of the backend server with ssl backend authentication")
+}
+
+// backendAuthFromFlags returns the backend authentication of the flags, the secrets
+// of the authentication type not given as values are read from their file or
+// environment variable. Only one file can be stdin.
+func backendAuthFromFlags() (backendAuth, error) {
+	b := backendAuthFlags
+	secrets := []struct {
+		authType string
+		value    *string
+		file     string
+		env      string
+	}{
+		{"basic", &b.Password, backendSecretFiles.password, backendPasswordEnv},
+		{"apikey", &b.APIKey, backendSecretFiles.apiKey, backendAPIKeyEnv},
+		{"ssl", &b.CertPassword, backendSecretFiles.certPassword, backendCertPasswordEnv},
+	}
+	stdin := 0
+	for _, secret := range secrets {
+		if secret.file == "-" {
+			stdin++
+		}
+	}
+	if stdin > 1 {
+		return b, fmt.Errorf("only one backend secret can be read from stdin")
+	}
+	for _, secret := range secrets {
+		if secret.authType != b.Type || *secret.value != "" {
+			continue
+		}
+		if secret.file == "" {
+			*secret.value = os.Getenv(secret.env)
+			continue
+		}
+		var content []byte
+		var err error
+		if secret.file == "-" {
+			content, err = ioutil.ReadAll(os.Stdin)
+		} else {
+			content, err = ioutil.ReadFile(secret.file)
+		}
+		if err != nil {
+			return b, err
+		}
+		*secret.value = strings.TrimRight(string(content), "\r\n")
+	}
+	return b, nil
+}
+
+// authenticationProfile returns the outbound authentication profile, files are
+// resolved with path
+func (b backendAuth) authenticationProfile(path func(string) string) (apimgr.AuthenticationProfile, error) {
+	profile := apimgr.AuthenticationProfile{Name: "_default", IsDefault: true, Parameters: map[string]interface{}{}}
+	switch b.Type {
+	case "none":
+		profile.Type_ = "none"
+	case "basic":
+		if b.Username == "" {
+			return profile, fmt.Errorf("basic backend authentication requires a username")
+		}
+		profile.Type_ = "http_basic"
+		profile.Parameters["username"] = b.Username
+		profile.Parameters["password"] = b.Password
+	case "apikey":
+		if b.APIKey == "" {
+			return profile, fmt.Errorf("apikey backend authentication requires an API key")
+		}
+		if b.APIKeyField == "" {
+			b.APIKeyField = "KeyId"
+		}
+		if b.TakeFrom == "" {
+			b.TakeFrom = "HEADER"
+		}
+		if b.TakeFrom != "HEADER" && b.TakeFrom != "QUERY" {
+			return profile, fmt.Errorf("the backend API key is sent in HEADER or QUERY, not %v", b.TakeFrom)
+		}
+		profile.Type_ = "apiKey"
+		profile.Parameters["apiKey"] = b.APIKey
+		profile.Parameters["apiKeyField"] = b.APIKeyField
+		profile.Parameters["takeFrom"] = b.TakeFrom
+	case "oauth":
+		if b.OAuthProvider == "" {
+			return profile, fmt.Errorf("oauth backend authentication requires the OAuth provider profile")
+		}
+		if b.OAuthOwner == "" {
+			b.OAuthOwner = "${authentication.subject.id}"
+		}
+		profile.Type_ = "oauth"
+		profile.Parameters["providerProfile"] = oauthProviderKey(b.OAuthProvider)
+		profile.Parameters["ownerId"] = b.OAuthOwner
+	case "ssl":
+		if b.Cert == "" {
+			return profile, fmt.Errorf("ssl backend authentication requires the client certificate file")
+		}
+		pfx, err := ioutil.ReadFile(path(b.Cert))
+		if err != nil {
+			return profile, err
+		}
+		profile.Type_ = "ssl"
+		profile.Parameters["source"] = "file"
+		profile.Parameters["certFile"] = b.Cert
+		profile.Parameters["pfx"] = "data:application/x-pkcs12;base64," + base64.StdEncoding.EncodeToString(pfx)
+		profile.Parameters["password"] = b.CertPassword
+		profile.Parameters["trustAll"] = b.TrustAll
+	default:
+		return profile, fmt.Errorf("unknown backend authentication %q, allowed: none, basic, apikey, oauth, ssl", b.Type)
+	}
+	return profile, nil
+}
+
+// setBackendAuth makes the profile the default outbound authentication of the proxy
+func setBackendAuth(proxy *apimgr.VirtualizedApi, profile apimgr.AuthenticationProfile) {
+	profiles := []apimgr.AuthenticationProfile{profile}
+	for _, p := range proxy.AuthenticationProfiles {
+		if p.Name != profile.Name {
+			p.IsDefault = false
+			profiles = append(profiles, p)
+		}
+	}
+	proxy.AuthenticationProfiles = profiles
+
+	// the map is shared with the live proxy the changes are compared to
+	outboundProfiles := map[string]apimgr.OutboundProfile{}
+	for name, outbound := range proxy.OutboundProfiles {
+		outboundProfiles[name] = outbound
+	}
+	outbound := outboundProfiles["_default"]
+	outbound.AuthenticationProfile = profile.Name
+	outboundProfiles["_default"] = outbound
+	proxy.OutboundProfiles = outboundProfiles
+}
+
+// oauthProviderKey returns the entity store key of an OAuth client provider profile
+// given by name, a key is returned as is
+func oauthProviderKey(provider string) string {
+	if strings.HasPrefix(provider, "<key") {
+		return provider
+	}
+	return "<key type='OAuthAppProfile'><id field='name' value='" + xmlAttr(provider) + "'/></key>"
+}
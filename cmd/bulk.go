@@ -0,0 +1,290 @@
+/*
+Copyright © 2020 Axway, Inc. <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/skckadiyala/apimanager/apimgr"
+	"github.com/skckadiyala/kubecrt-vms/utils"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// selectors of the proxies publish, unpublish and delete proxy act on, --tag is
+// shared with the API Catalog tags of publish
+var (
+	selectOrg       string
+	selectState     string
+	selectNameRegex string
+	selectAll       bool
+	selectFile      string
+	assumeYes       bool
+	parallel        int
+)
+
+var selectorFlags = []string{"org", "tag", "state", "name-regex", "all", "from-file"}
+
+// addSelectorFlags adds the flags selecting several proxies, withTag is false when
+// the command has a --tag flag already
+func addSelectorFlags(cmd *cobra.Command, withTag bool) {
+	cmd.Flags().StringVar(&selectOrg, "org", "", "select the proxies of the organization")
+	if withTag {
+		cmd.Flags().StringArrayVar(&catalogTags, "tag", nil, "select the proxies with the API Catalog tag key=value, or with the key, repeatable")
+	}
+	cmd.Flags().StringVar(&selectState, "state", "", "select the proxies in the state: published|unpublished|active|deprecated|retired")
+	cmd.Flags().StringVar(&selectNameRegex, "name-regex", "", "select the proxies with a name matching the regular expression")
+	cmd.Flags().BoolVar(&selectAll, "all", false, "select all proxies")
+	cmd.Flags().StringVar(&selectFile, "from-file", "", "select the proxies named in the file, one name per line")
+	cmd.Flags().BoolVarP(&assumeYes, "yes", "y", false, "don't ask for confirmation")
+	cmd.Flags().IntVar(&parallel, "parallel", 4, "number of proxies changed at the same time")
+}
+
+// givenSelectors returns the selector flags given, --all only selects when true
+func givenSelectors(cmd *cobra.Command) []string {
+	given := []string{}
+	for _, flag := range selectorFlags {
+		if flag == "all" && !selectAll {
+			continue
+		}
+		if cmd.Flags().Changed(flag) {
+			given = append(given, flag)
+		}
+	}
+	return given
+}
+
+// selecting reports whether the command acts on the proxies given by selectors
+// instead of the one given by -n
+func selecting(cmd *cobra.Command) bool {
+	return !cmd.Flags().Changed("name") && len(givenSelectors(cmd)) != 0
+}
+
+// checkSelection exits when neither -n nor a selector is given, or both are. The
+// --tag of a command with API Catalog flags sets the tags of the proxy given by -n.
+func checkSelection(cmd *cobra.Command) {
+	if cmd.Flags().Changed("name") {
+		for _, flag := range givenSelectors(cmd) {
+			if flag == "tag" && cmd.Flags().Lookup("display-name") != nil {
+				continue
+			}
+			utils.PrettyPrintErr("-n can't be combined with the selector --%v", flag)
+			os.Exit(1)
+		}
+	}
+	if name == "" && !selecting(cmd) {
+		utils.PrettyPrintErr("A proxy name is required, use -n or the selectors --org, --tag, --state, --name-regex, --all or --from-file")
+		os.Exit(1)
+	}
+}
+
+// selectProxies returns the proxies matching all the selectors
+func selectProxies(cfg *apimgr.Configuration) ([]apimgr.VirtualizedApi, error) {
+	client := apimgr.NewAPIClient(cfg)
+
+	proxies, _, err := client.APIProxyRegistrationApi.ProxiesGet(context.Background(), &apimgr.ProxiesGetOpts{})
+	if err != nil {
+		return nil, fmt.Errorf("error listing the proxies: %v", err)
+	}
+
+	var orgID string
+	if selectOrg != "" {
+		if orgID, err = resolveOrganizationID(cfg, selectOrg); err != nil {
+			return nil, err
+		}
+	}
+	var nameRegex *regexp.Regexp
+	if selectNameRegex != "" {
+		if nameRegex, err = regexp.Compile(selectNameRegex); err != nil {
+			return nil, fmt.Errorf("invalid --name-regex: %v", err)
+		}
+	}
+	switch selectState {
+	case "", "published", "unpublished", "active", "deprecated", "retired":
+	default:
+		return nil, fmt.Errorf("unknown state %q, allowed: published|unpublished|active|deprecated|retired", selectState)
+	}
+	var names map[string]bool
+	if selectFile != "" {
+		if names, err = readProxyNames(selectFile); err != nil {
+			return nil, err
+		}
+		found := map[string]bool{}
+		for _, proxy := range proxies {
+			found[proxy.Name] = true
+		}
+		missing := []string{}
+		for name := range names {
+			if !found[name] {
+				missing = append(missing, name)
+			}
+		}
+		if len(missing) != 0 {
+			return nil, fmt.Errorf("proxies of %v not found: %v", selectFile, strings.Join(missing, ", "))
+		}
+	}
+
+	selected := []apimgr.VirtualizedApi{}
+	for _, proxy := range proxies {
+		if orgID != "" && proxy.OrganizationId != orgID ||
+			!hasTags(proxy, catalogTags) ||
+			!hasState(proxy, selectState) ||
+			nameRegex != nil && !nameRegex.MatchString(proxy.Name) ||
+			names != nil && !names[proxy.Name] {
+			continue
+		}
+		selected = append(selected, proxy)
+	}
+	return selected, nil
+}
+
+// hasState reports whether the proxy is in the state or the lifecycle state
+func hasState(proxy apimgr.VirtualizedApi, state string) bool {
+	switch state {
+	case "":
+		return true
+	case "published", "unpublished":
+		return proxy.State == state
+	}
+	return strings.HasPrefix(proxyLifecycle(proxy), state)
+}
+
+// hasTags reports whether the proxy has all the tags, given as key=value or key
+func hasTags(proxy apimgr.VirtualizedApi, tags []string) bool {
+	for _, tag := range tags {
+		parts := strings.SplitN(tag, "=", 2)
+		values, ok := proxy.Tags[parts[0]]
+		if !ok {
+			return false
+		}
+		if len(parts) == 2 && !contains(values, parts[1]) {
+			return false
+		}
+	}
+	return true
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// readProxyNames reads the proxy names of a file, empty lines and lines starting with # are skipped
+func readProxyNames(fileName string) (map[string]bool, error) {
+	content, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	names := map[string]bool{}
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			names[line] = true
+		}
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no proxy names in %v", fileName)
+	}
+	return names, nil
+}
+
+// runBulk previews the selected proxies, asks for confirmation unless --yes is given
+// and runs the action on --parallel proxies at a time. The action returns the
+// result of a proxy, the command exits with 1 when it failed for any proxy.
+func runBulk(cfg *apimgr.Configuration, verb string, action func(apimgr.VirtualizedApi) (string, error)) {
+	proxies, err := selectProxies(cfg)
+	if err != nil {
+		utils.PrettyPrintErr("%v", err)
+		os.Exit(1)
+	}
+	if len(proxies) == 0 {
+		utils.PrettyPrintInfo("No Proxy matches the selectors")
+		return
+	}
+	proxyTable(cfg, proxies).printTable(false)
+	if err = confirm(fmt.Sprintf("%v %v proxies?", verb, len(proxies))); err != nil {
+		utils.PrettyPrintErr("%v", err)
+		os.Exit(1)
+	}
+
+	if parallel < 1 {
+		parallel = 1
+	}
+	results := make([]string, len(proxies))
+	failed := make([]bool, len(proxies))
+	slots := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	for i, proxy := range proxies {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(i int, proxy apimgr.VirtualizedApi) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			result, err := action(proxy)
+			if err != nil {
+				result, failed[i] = "failed: "+err.Error(), true
+			}
+			results[i] = result
+		}(i, proxy)
+	}
+	wg.Wait()
+
+	failures := 0
+	stdout := fmtDisplay()
+	fmt.Fprintf(stdout, "\nNAME\tVERSION\tRESULT\n")
+	for i, proxy := range proxies {
+		fmt.Fprintf(stdout, "%v\t%v\t%v\n", proxy.Name, proxy.Version, results[i])
+		if failed[i] {
+			failures++
+		}
+	}
+	stdout.Flush()
+	if failures != 0 {
+		utils.PrettyPrintErr("%v of %v proxies failed", failures, len(proxies))
+		os.Exit(1)
+	}
+	utils.PrettyPrintInfo("%v proxies done", len(proxies))
+}
+
+// confirm asks the question on the terminal, a terminal is required without --yes
+func confirm(question string) error {
+	if assumeYes {
+		return nil
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return errors.New("confirmation required, use --yes when not running in a terminal")
+	}
+	fmt.Printf("%v [y/N] ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	if answer != "y" && answer != "yes" {
+		return errors.New("cancelled")
+	}
+	return nil
+}
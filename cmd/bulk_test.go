@@ -0,0 +1,112 @@
+/*
+Copyright © 2020 Axway, Inc. <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/skckadiyala/apimanager/apimgr"
+	"github.com/spf13/cobra"
+)
+
+func TestHasTags(t *testing.T) {
+	proxy := apimgr.VirtualizedApi{Tags: map[string][]string{
+		"team":  {"avengers", "shield"},
+		"stage": {"beta"},
+		"empty": {},
+	}}
+	tests := []struct {
+		tags []string
+		want bool
+	}{
+		{nil, true},
+		{[]string{"team"}, true},
+		{[]string{"team=avengers"}, true},
+		{[]string{"team=shield", "stage=beta"}, true},
+		{[]string{"team=hydra"}, false},
+		{[]string{"team=avengers", "stage=ga"}, false},
+		{[]string{"owner"}, false},
+		{[]string{"empty"}, true},
+		{[]string{"empty="}, false},
+		{[]string{"team=avengers=assemble"}, false},
+	}
+	for _, test := range tests {
+		if got := hasTags(proxy, test.tags); got != test.want {
+			t.Errorf("hasTags(%v) = %v, want %v", test.tags, got, test.want)
+		}
+	}
+	if hasTags(apimgr.VirtualizedApi{}, []string{"team"}) {
+		t.Error("hasTags of a proxy without tags = true, want false")
+	}
+}
+
+func TestHasState(t *testing.T) {
+	published := apimgr.VirtualizedApi{State: "published"}
+	deprecated := apimgr.VirtualizedApi{State: "published", Deprecated: true}
+	retiring := apimgr.VirtualizedApi{State: "published", Deprecated: true, RetirementDate: 1798675200000}
+	retired := apimgr.VirtualizedApi{State: "unpublished", Deprecated: true, Retired: true}
+	tests := []struct {
+		name  string
+		proxy apimgr.VirtualizedApi
+		state string
+		want  bool
+	}{
+		{"any state", published, "", true},
+		{"published", published, "published", true},
+		{"not unpublished", published, "unpublished", false},
+		{"active", published, "active", true},
+		{"not deprecated", published, "deprecated", false},
+		{"deprecated", deprecated, "deprecated", true},
+		{"deprecated not active", deprecated, "active", false},
+		{"retiring is deprecated", retiring, "deprecated", true},
+		{"retiring not retired", retiring, "retired", false},
+		{"retired", retired, "retired", true},
+		{"retired unpublished", retired, "unpublished", true},
+		{"retired not deprecated", retired, "deprecated", false},
+	}
+	for _, test := range tests {
+		if got := hasState(test.proxy, test.state); got != test.want {
+			t.Errorf("%v: hasState(%v) = %v, want %v", test.name, test.state, got, test.want)
+		}
+	}
+}
+
+func TestSelecting(t *testing.T) {
+	tests := []struct {
+		args []string
+		want bool
+	}{
+		{[]string{}, false},
+		{[]string{"--all"}, true},
+		{[]string{"--all=false"}, false},
+		{[]string{"--all=false", "--yes"}, false},
+		{[]string{"--state", "retired"}, true},
+		{[]string{"--tag", "team=avengers"}, true},
+		{[]string{"-n", "Civil War"}, false},
+	}
+	for _, test := range tests {
+		cmd := &cobra.Command{}
+		cmd.Flags().StringVarP(&name, "name", "n", "", "proxy name")
+		addSelectorFlags(cmd, true)
+		if err := cmd.Flags().Parse(test.args); err != nil {
+			t.Fatalf("%v: %v", test.args, err)
+		}
+		if got := selecting(cmd); got != test.want {
+			t.Errorf("selecting(%v) = %v, want %v", test.args, got, test.want)
+		}
+	}
+}
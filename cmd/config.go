@@ -0,0 +1,578 @@
+/*
+Copyright © 2020 Axway, Inc. <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/skckadiyala/kubecrt-vms/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v2"
+)
+
+// defaultContext is the context used by login when none is given, single host
+// configuration files are read as this context
+const defaultContext = "default"
+
+// configFile is the content of $HOME/.apimanager.yaml
+type configFile struct {
+	CurrentContext string               `yaml:"currentContext,omitempty"`
+	Contexts       map[string]configAPI `yaml:"contexts,omitempty"`
+	// SecurityProfiles is the library of named security profiles, each is a
+	// SecurityProfile or a list of them as written in the profile file
+	SecurityProfiles map[string]interface{} `yaml:"securityProfiles,omitempty"`
+	// CorsProfiles is the library of named CORS profiles
+	CorsProfiles map[string]corsSettings `yaml:"corsProfiles,omitempty"`
+
+	// single host configuration written by older versions
+	APIManagerHost string `yaml:"apiManagerHost,omitempty"`
+	APIManagerPort string `yaml:"apiManagerPort,omitempty"`
+	Authorization  string `yaml:"authorization,omitempty"`
+}
+
+var (
+	configCmd = &cobra.Command{
+		Use:   "config",
+		Short: "Manage the API Manager contexts",
+		Long: `Manage the API Manager contexts stored in $HOME/.apimanager.yaml. A context
+is created with 'apimanager login --context <name>'.
+
+For example:
+
+  # List all contexts
+  apimanager config get-contexts
+
+  # Run all commands against prod
+  apimanager config use-context prod
+
+  # Run a single command against dev
+  apimanager list proxies --context dev
+`,
+	}
+	getContextsCmd = &cobra.Command{
+		Use:   "get-contexts",
+		Short: "List all contexts",
+		Args:  cobra.NoArgs,
+		Run:   getContexts,
+	}
+	currentContextCmd = &cobra.Command{
+		Use:   "current-context",
+		Short: "Display the current context",
+		Args:  cobra.NoArgs,
+		Run:   currentContext,
+	}
+	useContextCmd = &cobra.Command{
+		Use:   "use-context <name>",
+		Short: "Set the current context",
+		Args:  cobra.ExactArgs(1),
+		Run:   useContext,
+	}
+	renameContextCmd = &cobra.Command{
+		Use:   "rename-context <name> <new-name>",
+		Short: "Rename a context",
+		Args:  cobra.ExactArgs(2),
+		Run:   renameContext,
+	}
+	deleteContextCmd = &cobra.Command{
+		Use:   "delete-context <name>",
+		Short: "Delete a context",
+		Args:  cobra.ExactArgs(1),
+		Run:   deleteContext,
+	}
+	setSecurityProfileCmd = &cobra.Command{
+		Use:   "set-security-profile <name>",
+		Short: "Add a security profile to the library",
+		Long: `Add a security profile to the library in $HOME/.apimanager.yaml, proxies
+use it with -s <name>. A profile of the library replaces the built-in profile
+with the same name.
+
+The file describes a security profile, or a list of them, as yaml or json:
+
+  name: _default
+  isDefault: true
+  devices:
+  - name: API Key
+    type: apiKey
+    order: 1
+    properties:
+      apiKeyFieldName: X-API-Key
+      takeFrom: HEADER
+      removeCredentialsOnSuccess: true
+
+For example:
+
+  # Add the apikey-header profile
+  apimanager config set-security-profile apikey-header -f apikey-header.yaml
+`,
+		Args: cobra.ExactArgs(1),
+		Run:  setSecurityProfile,
+	}
+	getSecurityProfilesCmd = &cobra.Command{
+		Use:   "get-security-profiles",
+		Short: "List the security profiles",
+		Args:  cobra.NoArgs,
+		Run:   getSecurityProfilesList,
+	}
+	deleteSecurityProfileCmd = &cobra.Command{
+		Use:   "delete-security-profile <name>",
+		Short: "Delete a security profile from the library",
+		Args:  cobra.ExactArgs(1),
+		Run:   deleteSecurityProfile,
+	}
+	setCorsProfileCmd = &cobra.Command{
+		Use:   "set-cors-profile <name>",
+		Short: "Add a CORS profile to the library",
+		Long: `Add a CORS profile to the library in $HOME/.apimanager.yaml, proxies use it
+with --cors-profile <name>. An existing profile with the same name is replaced.
+
+For example:
+
+  # Allow the web portal to call proxies
+  apimanager config set-cors-profile portal --cors-origins https://portal.example.com --cors-headers Authorization,Content-Type
+`,
+		Args: cobra.ExactArgs(1),
+		Run:  setCorsProfile,
+	}
+	getCorsProfilesCmd = &cobra.Command{
+		Use:   "get-cors-profiles",
+		Short: "List the CORS profiles",
+		Args:  cobra.NoArgs,
+		Run:   getCorsProfilesList,
+	}
+	deleteCorsProfileCmd = &cobra.Command{
+		Use:   "delete-cors-profile <name>",
+		Short: "Delete a CORS profile from the library",
+		Args:  cobra.ExactArgs(1),
+		Run:   deleteCorsProfile,
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(getContextsCmd)
+	configCmd.AddCommand(currentContextCmd)
+	configCmd.AddCommand(useContextCmd)
+	configCmd.AddCommand(renameContextCmd)
+	configCmd.AddCommand(deleteContextCmd)
+	configCmd.AddCommand(setSecurityProfileCmd)
+	configCmd.AddCommand(getSecurityProfilesCmd)
+	configCmd.AddCommand(deleteSecurityProfileCmd)
+	configCmd.AddCommand(setCorsProfileCmd)
+	configCmd.AddCommand(getCorsProfilesCmd)
+	configCmd.AddCommand(deleteCorsProfileCmd)
+
+	setSecurityProfileCmd.Flags().StringVarP(&securityProfileFile, "file", "f", "", "yaml or json file describing the security profile")
+	setSecurityProfileCmd.MarkFlagRequired("file")
+	addCorsFlags(setCorsProfileCmd)
+	setCorsProfileCmd.MarkFlagRequired("cors-origins")
+}
+
+// configFileName returns the location of the config file
+func configFileName() string {
+	if cfgFile != "" {
+		return cfgFile
+	}
+	if used := viper.ConfigFileUsed(); used != "" {
+		return used
+	}
+	home, err := homedir.Dir()
+	if err != nil {
+		home = os.Getenv("HOME")
+	}
+	return filepath.Join(home, ".apimanager.yaml")
+}
+
+// loadConfigFile reads the config file, a missing file is an empty configuration
+func loadConfigFile() (configFile, error) {
+	conf := configFile{}
+	content, err := ioutil.ReadFile(configFileName())
+	if os.IsNotExist(err) {
+		return conf, nil
+	}
+	if err != nil {
+		return conf, err
+	}
+	if err = yaml.Unmarshal(content, &conf); err != nil {
+		return conf, err
+	}
+	if conf.Contexts == nil {
+		conf.Contexts = map[string]configAPI{}
+	}
+	// the plaintext credentials of an older version are rewritten even when dropped
+	plaintext := conf.Authorization != ""
+	if conf.APIManagerHost != "" {
+		if _, ok := conf.Contexts[defaultContext]; !ok {
+			conf.Contexts[defaultContext] = configAPI{
+				APIManagerHost: conf.APIManagerHost,
+				APIManagerPort: conf.APIManagerPort,
+				Authorization:  conf.Authorization,
+			}
+		}
+		if conf.CurrentContext == "" {
+			conf.CurrentContext = defaultContext
+		}
+		conf.APIManagerHost, conf.APIManagerPort = "", ""
+	}
+	conf.Authorization = ""
+	return conf, storePlaintextCredentials(&conf, plaintext)
+}
+
+// storePlaintextCredentials moves the credentials older versions wrote to the config
+// file into the credential store of their context and rewrites the file without them
+func storePlaintextCredentials(conf *configFile, rewrite bool) error {
+	for name, api := range conf.Contexts {
+		if api.Authorization == "" {
+			continue
+		}
+		if err := getCredentialStore(api).store(name, api.Authorization); err != nil {
+			return fmt.Errorf("unable to move the credentials of context %v to the credential store: %v", name, err)
+		}
+		api.Authorization = ""
+		conf.Contexts[name] = api
+		rewrite = true
+	}
+	if !rewrite {
+		return nil
+	}
+	return saveConfigFile(*conf)
+}
+
+// saveConfigFile writes the config file readable by the owner only, single host
+// configuration is written as a context
+func saveConfigFile(conf configFile) error {
+	out, err := yaml.Marshal(conf)
+	if err != nil {
+		return err
+	}
+	if err = ioutil.WriteFile(configFileName(), out, 0600); err != nil {
+		return err
+	}
+	// WriteFile keeps the permissions of an existing file
+	return os.Chmod(configFileName(), 0600)
+}
+
+// currentConfig returns the name and configuration of the context selected by
+// --context or the current context
+func currentConfig() (string, configAPI, error) {
+	conf, err := loadConfigFile()
+	if err != nil {
+		return "", configAPI{}, err
+	}
+	name := contextName
+	if name == "" {
+		name = conf.CurrentContext
+	}
+	if name == "" && len(conf.Contexts) == 0 {
+		// no config file, the configuration may come from the environment
+		return "", configAPI{
+			APIManagerHost: viper.GetString("apimanagerhost"),
+			APIManagerPort: viper.GetString("apimanagerport"),
+			Authorization:  viper.GetString("authorization"),
+
+			CAFile:                viper.GetString("cafile"),
+			ClientCert:            viper.GetString("clientcert"),
+			ClientKey:             viper.GetString("clientkey"),
+			TLSServerName:         viper.GetString("tlsservername"),
+			InsecureSkipTLSVerify: viper.GetBool("insecureskiptlsverify"),
+		}, nil
+	}
+	if name == "" {
+		return name, configAPI{}, fmt.Errorf("current context is not set, use 'config use-context' command")
+	}
+	api, ok := conf.Contexts[name]
+	if !ok {
+		return name, api, fmt.Errorf("context %q not found", name)
+	}
+	return name, api, nil
+}
+
+func getContexts(cmd *cobra.Command, args []string) {
+	conf, err := loadConfigFile()
+	if err != nil {
+		utils.PrettyPrintErr("Error reading config file: %v", err)
+		return
+	}
+	if len(conf.Contexts) == 0 {
+		utils.PrettyPrintInfo("No contexts found, use 'login' command")
+		return
+	}
+	names := []string{}
+	for name := range conf.Contexts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	stdout := fmtDisplay()
+	fmt.Fprintf(stdout, "CURRENT\tNAME\tHOST\tPORT\n")
+	for _, name := range names {
+		current := ""
+		if name == conf.CurrentContext {
+			current = "*"
+		}
+		api := conf.Contexts[name]
+		fmt.Fprintf(stdout, "%v\t%v\t%v\t%v\n", current, name, api.APIManagerHost, api.APIManagerPort)
+	}
+	stdout.Flush()
+}
+
+func currentContext(cmd *cobra.Command, args []string) {
+	conf, err := loadConfigFile()
+	if err != nil {
+		utils.PrettyPrintErr("Error reading config file: %v", err)
+		return
+	}
+	if conf.CurrentContext == "" {
+		utils.PrettyPrintInfo("Current context is not set")
+		return
+	}
+	fmt.Println(conf.CurrentContext)
+}
+
+func useContext(cmd *cobra.Command, args []string) {
+	conf, err := loadConfigFile()
+	if err != nil {
+		utils.PrettyPrintErr("Error reading config file: %v", err)
+		return
+	}
+	if _, ok := conf.Contexts[args[0]]; !ok {
+		utils.PrettyPrintErr("Context %v not found", args[0])
+		return
+	}
+	conf.CurrentContext = args[0]
+	if err = saveConfigFile(conf); err != nil {
+		utils.PrettyPrintErr("Error writing config file: %v", err)
+		return
+	}
+	utils.PrettyPrintInfo("Switched to context %v", args[0])
+}
+
+func renameContext(cmd *cobra.Command, args []string) {
+	conf, err := loadConfigFile()
+	if err != nil {
+		utils.PrettyPrintErr("Error reading config file: %v", err)
+		return
+	}
+	api, ok := conf.Contexts[args[0]]
+	if !ok {
+		utils.PrettyPrintErr("Context %v not found", args[0])
+		return
+	}
+	if _, ok := conf.Contexts[args[1]]; ok {
+		utils.PrettyPrintErr("Context %v already exists", args[1])
+		return
+	}
+	if api.Authorization == "" {
+		// credentials are stored by context name, a logged out context has none
+		store := getCredentialStore(api)
+		if secret, err := store.get(args[0]); err == nil {
+			err = store.store(args[1], secret)
+			if err == nil {
+				err = store.erase(args[0])
+			}
+			if err == nil {
+				err = eraseSession(args[0], api)
+			}
+			if err != nil {
+				utils.PrettyPrintErr("Error moving the credentials of context %v: %v", args[0], err)
+				return
+			}
+		}
+	}
+	delete(conf.Contexts, args[0])
+	conf.Contexts[args[1]] = api
+	if conf.CurrentContext == args[0] {
+		conf.CurrentContext = args[1]
+	}
+	if err = saveConfigFile(conf); err != nil {
+		utils.PrettyPrintErr("Error writing config file: %v", err)
+		return
+	}
+	utils.PrettyPrintInfo("Context %v renamed to %v", args[0], args[1])
+}
+
+func deleteContext(cmd *cobra.Command, args []string) {
+	conf, err := loadConfigFile()
+	if err != nil {
+		utils.PrettyPrintErr("Error reading config file: %v", err)
+		return
+	}
+	api, ok := conf.Contexts[args[0]]
+	if !ok {
+		utils.PrettyPrintErr("Context %v not found", args[0])
+		return
+	}
+	err = getCredentialStore(api).erase(args[0])
+	if err == nil {
+		err = eraseSession(args[0], api)
+	}
+	if err != nil {
+		utils.PrettyPrintErr("Error removing the credentials of context %v: %v", args[0], err)
+		return
+	}
+	delete(conf.Contexts, args[0])
+	if conf.CurrentContext == args[0] {
+		conf.CurrentContext = ""
+	}
+	if err = saveConfigFile(conf); err != nil {
+		utils.PrettyPrintErr("Error writing config file: %v", err)
+		return
+	}
+	utils.PrettyPrintInfo("Context %v deleted", args[0])
+}
+
+func setSecurityProfile(cmd *cobra.Command, args []string) {
+	_, value, err := readSecurityProfiles(securityProfileFile)
+	if err != nil {
+		utils.PrettyPrintErr("%v", err)
+		return
+	}
+	conf, err := loadConfigFile()
+	if err != nil {
+		utils.PrettyPrintErr("Error reading config file: %v", err)
+		return
+	}
+	if conf.SecurityProfiles == nil {
+		conf.SecurityProfiles = map[string]interface{}{}
+	}
+	conf.SecurityProfiles[args[0]] = value
+	if err = saveConfigFile(conf); err != nil {
+		utils.PrettyPrintErr("Error writing config file: %v", err)
+		return
+	}
+	utils.PrettyPrintInfo("Security profile %v stored", args[0])
+}
+
+func getSecurityProfilesList(cmd *cobra.Command, args []string) {
+	conf, err := loadConfigFile()
+	if err != nil {
+		utils.PrettyPrintErr("Error reading config file: %v", err)
+		return
+	}
+	names := []string{}
+	for name := range conf.SecurityProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	stdout := fmtDisplay()
+	fmt.Fprintf(stdout, "NAME\tSOURCE\tDEVICES\n")
+	for _, name := range names {
+		devices := "invalid"
+		if profiles, err := decodeSecurityProfiles(conf.SecurityProfiles[name]); err == nil {
+			devices = securityDeviceTypes(profiles)
+		}
+		fmt.Fprintf(stdout, "%v\t%v\t%v\n", name, "config", devices)
+	}
+	for _, name := range builtinSecurityProfiles {
+		if _, ok := conf.SecurityProfiles[name]; ok {
+			continue
+		}
+		profiles, _ := builtinSecurityProfile(name)
+		fmt.Fprintf(stdout, "%v\t%v\t%v\n", name, "built-in", securityDeviceTypes(profiles))
+	}
+	stdout.Flush()
+}
+
+func deleteSecurityProfile(cmd *cobra.Command, args []string) {
+	conf, err := loadConfigFile()
+	if err != nil {
+		utils.PrettyPrintErr("Error reading config file: %v", err)
+		return
+	}
+	if _, ok := conf.SecurityProfiles[args[0]]; !ok {
+		utils.PrettyPrintErr("Security profile %v not found", args[0])
+		return
+	}
+	delete(conf.SecurityProfiles, args[0])
+	if err = saveConfigFile(conf); err != nil {
+		utils.PrettyPrintErr("Error writing config file: %v", err)
+		return
+	}
+	utils.PrettyPrintInfo("Security profile %v deleted", args[0])
+}
+
+func setCorsProfile(cmd *cobra.Command, args []string) {
+	conf, err := loadConfigFile()
+	if err != nil {
+		utils.PrettyPrintErr("Error reading config file: %v", err)
+		return
+	}
+	if conf.CorsProfiles == nil {
+		conf.CorsProfiles = map[string]corsSettings{}
+	}
+	if err = corsFlags.checkMethods(); err != nil {
+		utils.PrettyPrintErr("Error storing CORS profile %v: %v", args[0], err)
+		return
+	}
+	conf.CorsProfiles[args[0]] = corsFlags
+	if err = saveConfigFile(conf); err != nil {
+		utils.PrettyPrintErr("Error writing config file: %v", err)
+		return
+	}
+	utils.PrettyPrintInfo("CORS profile %v stored", args[0])
+}
+
+func getCorsProfilesList(cmd *cobra.Command, args []string) {
+	conf, err := loadConfigFile()
+	if err != nil {
+		utils.PrettyPrintErr("Error reading config file: %v", err)
+		return
+	}
+	if len(conf.CorsProfiles) == 0 {
+		utils.PrettyPrintInfo("No CORS profiles found, use 'config set-cors-profile' command")
+		return
+	}
+	names := []string{}
+	for name := range conf.CorsProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	stdout := fmtDisplay()
+	fmt.Fprintf(stdout, "NAME\tORIGINS\tHEADERS\tEXPOSED HEADERS\tCREDENTIALS\tMAX AGE\n")
+	for _, name := range names {
+		c := conf.CorsProfiles[name]
+		fmt.Fprintf(stdout, "%v\t%v\t%v\t%v\t%v\t%v\n", name, strings.Join(c.Origins, ","), strings.Join(c.AllowedHeaders, ","),
+			strings.Join(c.ExposedHeaders, ","), c.SupportCredentials, c.MaxAgeSeconds)
+	}
+	stdout.Flush()
+}
+
+func deleteCorsProfile(cmd *cobra.Command, args []string) {
+	conf, err := loadConfigFile()
+	if err != nil {
+		utils.PrettyPrintErr("Error reading config file: %v", err)
+		return
+	}
+	if _, ok := conf.CorsProfiles[args[0]]; !ok {
+		utils.PrettyPrintErr("CORS profile %v not found", args[0])
+		return
+	}
+	delete(conf.CorsProfiles, args[0])
+	if err = saveConfigFile(conf); err != nil {
+		utils.PrettyPrintErr("Error writing config file: %v", err)
+		return
+	}
+	utils.PrettyPrintInfo("CORS profile %v deleted", args[0])
+}
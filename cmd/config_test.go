@@ -0,0 +1,113 @@
+/*
+Copyright © 2020 Axway, Inc. <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfigFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		current string
+		want    map[string]configAPI
+		secrets map[string]string
+	}{
+		{
+			name:    "single host",
+			content: "apiManagerHost: marvel.com\napiManagerPort: \"8075\"\nauthorization: Basic bWFydmVs\n",
+			current: defaultContext,
+			want:    map[string]configAPI{defaultContext: {APIManagerHost: "marvel.com", APIManagerPort: "8075"}},
+			secrets: map[string]string{defaultContext: "Basic bWFydmVs"},
+		},
+		{
+			name:    "contexts",
+			content: "currentContext: prod\ncontexts:\n  prod:\n    apiManagerHost: marvel.com\n    apiManagerPort: \"8075\"\n  dev:\n    apiManagerHost: dev.marvel.com\n    apiManagerPort: \"8075\"\n",
+			current: "prod",
+			want: map[string]configAPI{
+				"prod": {APIManagerHost: "marvel.com", APIManagerPort: "8075"},
+				"dev":  {APIManagerHost: "dev.marvel.com", APIManagerPort: "8075"},
+			},
+		},
+		{
+			name:    "plaintext contexts",
+			content: "currentContext: dev\ncontexts:\n  dev:\n    apiManagerHost: dev.marvel.com\n    apiManagerPort: \"8075\"\n    authorization: Basic ZGV2\n",
+			current: "dev",
+			want:    map[string]configAPI{"dev": {APIManagerHost: "dev.marvel.com", APIManagerPort: "8075"}},
+			secrets: map[string]string{"dev": "Basic ZGV2"},
+		},
+		{
+			name:    "single host next to contexts",
+			content: "apiManagerHost: old.marvel.com\napiManagerPort: \"8075\"\ncontexts:\n  default:\n    apiManagerHost: marvel.com\n    apiManagerPort: \"8075\"\n",
+			current: defaultContext,
+			want:    map[string]configAPI{defaultContext: {APIManagerHost: "marvel.com", APIManagerPort: "8075"}},
+		},
+		{
+			name:    "no file",
+			content: "",
+			want:    map[string]configAPI{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			home := testHome(t)
+			fileName := filepath.Join(home, ".apimanager.yaml")
+			if test.content != "" {
+				if err := ioutil.WriteFile(fileName, []byte(test.content), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			conf, err := loadConfigFile()
+			if err != nil {
+				t.Fatalf("loadConfigFile: %v", err)
+			}
+			if conf.CurrentContext != test.current {
+				t.Errorf("current context = %q, want %q", conf.CurrentContext, test.current)
+			}
+			if conf.Contexts == nil {
+				conf.Contexts = map[string]configAPI{}
+			}
+			if !reflect.DeepEqual(conf.Contexts, test.want) {
+				t.Errorf("contexts = %+v, want %+v", conf.Contexts, test.want)
+			}
+			if conf.APIManagerHost != "" || conf.Authorization != "" {
+				t.Errorf("single host configuration kept: %+v", conf)
+			}
+			for context, secret := range test.secrets {
+				got, err := fileStore{}.get(context)
+				if err != nil || got != secret {
+					t.Errorf("stored credentials of %v = %q, %v, want %q", context, got, err, secret)
+				}
+			}
+			if len(test.secrets) != 0 {
+				content, err := ioutil.ReadFile(fileName)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if strings.Contains(string(content), "authorization") {
+					t.Errorf("config file still holds credentials:\n%s", content)
+				}
+			}
+		})
+	}
+}
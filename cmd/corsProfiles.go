@@ -0,0 +1,176 @@
+/*
+Copyright © 2020 Axway, Inc. <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/skckadiyala/apimanager/apimgr"
+	"github.com/spf13/cobra"
+)
+
+// corsSettings is a CORS profile of the library in the config file or of a manifest.
+// API Manager answers preflight requests with the methods of the API, methods are
+// accepted to reject them with an explanation instead of ignoring them.
+type corsSettings struct {
+	Origins            []string `yaml:"origins,omitempty" json:"origins,omitempty"`
+	Methods            []string `yaml:"methods,omitempty" json:"methods,omitempty"`
+	AllowedHeaders     []string `yaml:"allowedHeaders,omitempty" json:"allowedHeaders,omitempty"`
+	ExposedHeaders     []string `yaml:"exposedHeaders,omitempty" json:"exposedHeaders,omitempty"`
+	SupportCredentials bool     `yaml:"supportCredentials,omitempty" json:"supportCredentials,omitempty"`
+	MaxAgeSeconds      int32    `yaml:"maxAgeSeconds,omitempty" json:"maxAgeSeconds,omitempty"`
+}
+
+var (
+	corsProfileName string
+	corsFlags       corsSettings
+)
+
+// addCorsFlags adds the flags describing a CORS profile
+func addCorsFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&corsFlags.Origins, "cors-origins", nil, "origins allowed to call the proxy, * for any")
+	cmd.Flags().StringSliceVar(&corsFlags.Methods, "cors-methods", nil, "methods allowed in CORS requests, not supported: API Manager allows the methods of the API")
+	cmd.Flags().StringSliceVar(&corsFlags.AllowedHeaders, "cors-headers", nil, "request headers allowed in CORS requests")
+	cmd.Flags().StringSliceVar(&corsFlags.ExposedHeaders, "cors-exposed-headers", nil, "response headers exposed to the browser")
+	cmd.Flags().BoolVar(&corsFlags.SupportCredentials, "cors-credentials", false, "allow CORS requests with credentials")
+	cmd.Flags().Int32Var(&corsFlags.MaxAgeSeconds, "cors-max-age", 0, "seconds the browser caches the preflight response")
+}
+
+// corsFlagsChanged reports whether any CORS flag is given
+func corsFlagsChanged(cmd *cobra.Command) bool {
+	for _, flag := range []string{"cors-profile", "cors-origins", "cors-methods", "cors-headers", "cors-exposed-headers", "cors-credentials", "cors-max-age"} {
+		if cmd.Flags().Changed(flag) {
+			return true
+		}
+	}
+	return false
+}
+
+// corsProfileFromFlags returns the CORS profile given by --cors-profile, with the
+// other CORS flags that are given applied to it
+func corsProfileFromFlags(cmd *cobra.Command) (apimgr.CorsProfile, error) {
+	settings := corsSettings{}
+	name := "_default"
+	if cmd.Flags().Changed("cors-profile") {
+		var err error
+		if settings, err = getCorsProfile(corsProfileName); err != nil {
+			return apimgr.CorsProfile{}, err
+		}
+		name = corsProfileName
+	}
+	if cmd.Flags().Changed("cors-origins") {
+		settings.Origins = corsFlags.Origins
+	}
+	if cmd.Flags().Changed("cors-methods") {
+		settings.Methods = corsFlags.Methods
+	}
+	if cmd.Flags().Changed("cors-headers") {
+		settings.AllowedHeaders = corsFlags.AllowedHeaders
+	}
+	if cmd.Flags().Changed("cors-exposed-headers") {
+		settings.ExposedHeaders = corsFlags.ExposedHeaders
+	}
+	if cmd.Flags().Changed("cors-credentials") {
+		settings.SupportCredentials = corsFlags.SupportCredentials
+	}
+	if cmd.Flags().Changed("cors-max-age") {
+		settings.MaxAgeSeconds = corsFlags.MaxAgeSeconds
+	}
+	return settings.corsProfile(name)
+}
+
+// corsProfile returns the CORS profile given by the corsProfile or the cors field of the manifest
+func (m manifest) corsProfile() (apimgr.CorsProfile, error) {
+	if m.CorsProfile != "" && m.Cors != nil {
+		return apimgr.CorsProfile{}, fmt.Errorf("either corsProfile or cors is allowed")
+	}
+	if m.Cors != nil {
+		return m.Cors.corsProfile("_default")
+	}
+	settings, err := getCorsProfile(m.CorsProfile)
+	if err != nil {
+		return apimgr.CorsProfile{}, err
+	}
+	return settings.corsProfile(m.CorsProfile)
+}
+
+// getCorsProfile returns the CORS profile of the library
+func getCorsProfile(name string) (corsSettings, error) {
+	conf, err := loadConfigFile()
+	if err != nil {
+		return corsSettings{}, err
+	}
+	settings, ok := conf.CorsProfiles[name]
+	if !ok {
+		return settings, fmt.Errorf("CORS profile %v not found, use 'config get-cors-profiles' command", name)
+	}
+	return settings, nil
+}
+
+// corsProfile returns the API Manager CORS profile with the settings
+func (c corsSettings) corsProfile(name string) (apimgr.CorsProfile, error) {
+	if len(c.Origins) == 0 {
+		return apimgr.CorsProfile{}, fmt.Errorf("CORS profile %v has no origins", name)
+	}
+	if err := c.checkMethods(); err != nil {
+		return apimgr.CorsProfile{}, fmt.Errorf("CORS profile %v: %v", name, err)
+	}
+	return apimgr.CorsProfile{
+		Name:               name,
+		IsDefault:          true,
+		Origins:            c.Origins,
+		AllowedHeaders:     c.AllowedHeaders,
+		ExposedHeaders:     c.ExposedHeaders,
+		SupportCredentials: c.SupportCredentials,
+		MaxAgeSeconds:      c.MaxAgeSeconds,
+	}, nil
+}
+
+// checkMethods returns an error when methods are set, an API Manager CORS profile
+// can't restrict them
+func (c corsSettings) checkMethods() error {
+	if len(c.Methods) == 0 {
+		return nil
+	}
+	return fmt.Errorf("methods %v can't be set, API Manager answers preflight requests with the methods of the API; restrict the methods of the backend API instead", strings.Join(c.Methods, ","))
+}
+
+// setProxyCors makes the profile the CORS profile of the default inbound profile of the proxy
+func setProxyCors(proxy *apimgr.VirtualizedApi, profile apimgr.CorsProfile) {
+	profiles := []apimgr.CorsProfile{profile}
+	for _, p := range proxy.CorsProfiles {
+		if p.Name != profile.Name {
+			p.IsDefault = false
+			profiles = append(profiles, p)
+		}
+	}
+	proxy.CorsProfiles = profiles
+
+	// the map is shared with the live proxy the changes are compared to
+	inboundProfiles := map[string]apimgr.InboundProfile{}
+	for name, inbound := range proxy.InboundProfiles {
+		inboundProfiles[name] = inbound
+	}
+	inbound := inboundProfiles["_default"]
+	inbound.CorsProfile = profile.Name
+	if inbound.SecurityProfile == "" {
+		inbound.SecurityProfile = "_default"
+	}
+	inboundProfiles["_default"] = inbound
+	proxy.InboundProfiles = inboundProfiles
+}
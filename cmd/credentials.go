@@ -0,0 +1,250 @@
+/*
+Copyright © 2020 Axway, Inc. <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	homedir "github.com/mitchellh/go-homedir"
+	"golang.org/x/crypto/scrypt"
+)
+
+// credentialsKeyEnv holds an optional passphrase the credentials file is encrypted with.
+// Without it a random key is generated and stored next to the credentials file.
+const credentialsKeyEnv = "APIMANAGER_CREDENTIALS_KEY"
+
+var credentialHelper string
+
+// credentialStore keeps the API Manager credentials of each context
+type credentialStore interface {
+	get(context string) (string, error)
+	store(context, secret string) error
+	erase(context string) error
+}
+
+// getCredentialStore returns the store configured for the context, the encrypted
+// file store unless a credential helper is set
+func getCredentialStore(api configAPI) credentialStore {
+	if api.CredentialHelper != "" {
+		return helperStore{command: api.CredentialHelper}
+	}
+	return fileStore{}
+}
+
+// helperStore runs an external program to keep the credentials, it is called as
+//
+//	<helper> get     context name on stdin, secret on stdout
+//	<helper> store   {"context": "...", "secret": "..."} on stdin
+//	<helper> erase   context name on stdin
+type helperStore struct {
+	command string
+}
+
+func (h helperStore) run(action string, input []byte) ([]byte, error) {
+	args := strings.Fields(h.command)
+	if len(args) == 0 {
+		return nil, errors.New("credential helper is empty")
+	}
+	out := bytes.Buffer{}
+	cm := exec.Command(args[0], append(args[1:], action)...)
+	cm.Stdin = bytes.NewReader(input)
+	cm.Stdout = &out
+	cm.Stderr = os.Stderr
+	if err := cm.Run(); err != nil {
+		return nil, fmt.Errorf("credential helper %s %s: %v", args[0], action, err)
+	}
+	return out.Bytes(), nil
+}
+
+func (h helperStore) get(context string) (string, error) {
+	out, err := h.run("get", []byte(context))
+	return strings.TrimSpace(string(out)), err
+}
+
+func (h helperStore) store(context, secret string) error {
+	input, err := json.Marshal(map[string]string{"context": context, "secret": secret})
+	if err != nil {
+		return err
+	}
+	_, err = h.run("store", input)
+	return err
+}
+
+func (h helperStore) erase(context string) error {
+	_, err := h.run("erase", []byte(context))
+	return err
+}
+
+// fileStore keeps the credentials AES-GCM encrypted in $HOME/.apimanager/credentials
+type fileStore struct{}
+
+func credentialsDir() (string, error) {
+	home, err := homedir.Dir()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(home, ".apimanager")
+	return dir, os.MkdirAll(dir, 0700)
+}
+
+// key returns the encryption key, salt is only used to derive it from a passphrase
+func (f fileStore) key(salt []byte) ([]byte, error) {
+	if passphrase := os.Getenv(credentialsKeyEnv); passphrase != "" {
+		return scrypt.Key([]byte(passphrase), salt, 32768, 8, 1, 32)
+	}
+	dir, err := credentialsDir()
+	if err != nil {
+		return nil, err
+	}
+	keyFile := filepath.Join(dir, "credentials.key")
+	key, err := ioutil.ReadFile(keyFile)
+	if err == nil && len(key) != 32 {
+		// a new key would make every stored credential unreadable
+		return nil, fmt.Errorf("credentials key %v is corrupted, restore it or remove it and the credentials file and login again", keyFile)
+	}
+	if err == nil {
+		return key, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+	key = make([]byte, 32)
+	if _, err = rand.Read(key); err != nil {
+		return nil, err
+	}
+	return key, ioutil.WriteFile(keyFile, key, 0600)
+}
+
+// entries are the encrypted secrets by context, each is base64(salt|nonce|ciphertext)
+func (f fileStore) entries() (map[string]string, string, error) {
+	entries := map[string]string{}
+	dir, err := credentialsDir()
+	if err != nil {
+		return nil, "", err
+	}
+	fileName := filepath.Join(dir, "credentials")
+	content, err := ioutil.ReadFile(fileName)
+	if os.IsNotExist(err) {
+		return entries, fileName, nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return entries, fileName, json.Unmarshal(content, &entries)
+}
+
+func (f fileStore) save(fileName string, entries map[string]string) error {
+	content, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = ioutil.WriteFile(fileName, content, 0600); err != nil {
+		return err
+	}
+	return os.Chmod(fileName, 0600)
+}
+
+func (f fileStore) get(context string) (string, error) {
+	entries, _, err := f.entries()
+	if err != nil {
+		return "", err
+	}
+	entry, ok := entries[context]
+	if !ok {
+		return "", fmt.Errorf("no credentials stored for context %q, use 'login' command", context)
+	}
+	data, err := base64.StdEncoding.DecodeString(entry)
+	if err != nil || len(data) < 16 {
+		return "", fmt.Errorf("credentials of context %q are corrupted", context)
+	}
+	key, err := f.key(data[:16])
+	if err != nil {
+		return "", err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	data = data[16:]
+	if len(data) < gcm.NonceSize() {
+		return "", fmt.Errorf("credentials of context %q are corrupted", context)
+	}
+	secret, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], []byte(context))
+	if err != nil {
+		return "", fmt.Errorf("unable to decrypt the credentials of context %q", context)
+	}
+	return string(secret), nil
+}
+
+func (f fileStore) store(context, secret string) error {
+	entries, fileName, err := f.entries()
+	if err != nil {
+		return err
+	}
+	salt := make([]byte, 16)
+	if _, err = rand.Read(salt); err != nil {
+		return err
+	}
+	key, err := f.key(salt)
+	if err != nil {
+		return err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return err
+	}
+	data := append(salt, nonce...)
+	data = gcm.Seal(data, nonce, []byte(secret), []byte(context))
+	entries[context] = base64.StdEncoding.EncodeToString(data)
+	return f.save(fileName, entries)
+}
+
+func (f fileStore) erase(context string) error {
+	entries, fileName, err := f.entries()
+	if err != nil {
+		return err
+	}
+	if _, ok := entries[context]; !ok {
+		return nil
+	}
+	delete(entries, context)
+	return f.save(fileName, entries)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
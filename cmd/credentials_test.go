@@ -0,0 +1,123 @@
+/*
+Copyright © 2020 Axway, Inc. <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	homedir "github.com/mitchellh/go-homedir"
+)
+
+// testHome makes a temporary directory the home directory for the test
+func testHome(t *testing.T) string {
+	home, err := ioutil.TempDir("", "apimanager")
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldHome, oldKey := os.Getenv("HOME"), os.Getenv(credentialsKeyEnv)
+	os.Setenv("HOME", home)
+	os.Unsetenv(credentialsKeyEnv)
+	homedir.DisableCache = true
+	t.Cleanup(func() {
+		os.Setenv("HOME", oldHome)
+		os.Setenv(credentialsKeyEnv, oldKey)
+		homedir.DisableCache = false
+		os.RemoveAll(home)
+	})
+	return home
+}
+
+func TestFileStore(t *testing.T) {
+	tests := []struct {
+		name       string
+		passphrase string
+	}{
+		{"generated key", ""},
+		{"passphrase", "I am Iron Man"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			home := testHome(t)
+			os.Setenv(credentialsKeyEnv, test.passphrase)
+			store := fileStore{}
+
+			secrets := map[string]string{"prod": "Basic cHJvZA==", "dev": "Basic ZGV2"}
+			for context, secret := range secrets {
+				if err := store.store(context, secret); err != nil {
+					t.Fatalf("store(%v): %v", context, err)
+				}
+			}
+			for context, secret := range secrets {
+				got, err := store.get(context)
+				if err != nil || got != secret {
+					t.Errorf("get(%v) = %q, %v, want %q", context, got, err, secret)
+				}
+			}
+
+			content, err := ioutil.ReadFile(filepath.Join(home, ".apimanager", "credentials"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, secret := range secrets {
+				if strings.Contains(string(content), secret) {
+					t.Errorf("credentials file holds %q in plain text", secret)
+				}
+			}
+
+			if err = store.erase("dev"); err != nil {
+				t.Fatalf("erase(dev): %v", err)
+			}
+			if _, err = store.get("dev"); err == nil {
+				t.Error("get(dev) after erase succeeded")
+			}
+			if _, err = store.get("prod"); err != nil {
+				t.Errorf("get(prod) after erasing dev: %v", err)
+			}
+		})
+	}
+}
+
+func TestFileStoreWrongKey(t *testing.T) {
+	home := testHome(t)
+	os.Setenv(credentialsKeyEnv, "I am Iron Man")
+	store := fileStore{}
+	if err := store.store("prod", "Basic cHJvZA=="); err != nil {
+		t.Fatal(err)
+	}
+
+	os.Setenv(credentialsKeyEnv, "I am Groot")
+	if _, err := store.get("prod"); err == nil {
+		t.Error("get with another passphrase succeeded")
+	}
+
+	// a corrupted key file is reported instead of replaced
+	os.Unsetenv(credentialsKeyEnv)
+	keyFile := filepath.Join(home, ".apimanager", "credentials.key")
+	if err := ioutil.WriteFile(keyFile, []byte("short"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.store("dev", "Basic ZGV2"); err == nil {
+		t.Error("store with a corrupted key succeeded")
+	}
+	if key, _ := ioutil.ReadFile(keyFile); string(key) != "short" {
+		t.Error("corrupted key was replaced")
+	}
+}
@@ -0,0 +1,375 @@
+/*
+Copyright © 2020 Axway, Inc. <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/skckadiyala/apimanager/apimgr"
+	"github.com/skckadiyala/kubecrt-vms/utils"
+	"github.com/spf13/cobra"
+)
+
+// server managed fields that are never compared
+var ignoredFields = map[string]bool{
+	"id":        true,
+	"dn":        true,
+	"createdOn": true,
+	"createdBy": true,
+}
+
+// fields holding secrets, their values are masked in the diff
+var secretFields = map[string]bool{
+	"password":     true,
+	"apiKey":       true,
+	"secret":       true,
+	"pfx":          true,
+	"certPassword": true,
+	"clientSecret": true,
+}
+
+// diffCmd represents the diff command
+var diffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Diff the live API Manager resources against manifests",
+	Long: `Diff the live API Manager resources against the manifests that would be applied.
+Server managed fields such as id, dn and createdOn are ignored. References to
+resources the manifests create are shown as <new Kind name>, and secrets are masked.
+
+Exit status is 0 when there are no differences, 1 when differences were found
+and 2 when the diff could not be computed.
+
+For example:
+
+  # Show the changes apply would make
+  apimanager diff -f marvel.yaml
+`,
+	Run: diff,
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+
+	diffCmd.Flags().StringSliceVarP(&manifestPaths, "file", "f", []string{}, "manifest files or directories to diff")
+	diffCmd.MarkFlagRequired("file")
+	diffCmd.Flags().BoolVarP(&recursive, "recursive", "R", false, "process the directories used in -f recursively")
+}
+
+func diff(cmd *cobra.Command, args []string) {
+	manifests, err := loadManifests(manifestPaths, recursive)
+	if err != nil {
+		utils.PrettyPrintErr("Error reading manifests: %v", err)
+		os.Exit(2)
+	}
+	sortManifests(manifests)
+
+	plannedResources = map[string]bool{}
+	for _, m := range manifests {
+		plannedResources[plannedKey(m.Kind, m.Name)] = true
+	}
+
+	cfg := getConfig()
+	drift, failed := false, false
+	for _, m := range manifests {
+		live, desired, err := diffFields(cfg, m)
+		if err != nil {
+			utils.PrettyPrintErr("%v: %v", m, err)
+			failed = true
+			continue
+		}
+		if printDiff(m, live, desired) {
+			drift = true
+		}
+	}
+	if failed {
+		os.Exit(2)
+	}
+	if drift {
+		os.Exit(1)
+	}
+}
+
+// diffFields returns the flattened live and desired fields of the manifest
+// resource, live is nil when the resource doesn't exist
+func diffFields(cfg *apimgr.Configuration, m manifest) (map[string]string, map[string]string, error) {
+	var live, desired interface{}
+	switch m.Kind {
+	case kindOrganization:
+		org, err := findOrganization(cfg, m.Name)
+		if err != nil {
+			return nil, nil, err
+		}
+		desired, err = desiredOrganization(cfg, m, org)
+		if err != nil {
+			return nil, nil, err
+		}
+		if org != nil {
+			live = *org
+		}
+	case kindUser:
+		user, err := findUser(cfg, m.Name)
+		if err != nil {
+			return nil, nil, err
+		}
+		desired, err = desiredUser(cfg, m, user)
+		if err != nil {
+			return nil, nil, err
+		}
+		if user != nil {
+			live = *user
+		}
+	case kindApplication:
+		app, err := findApplication(cfg, m.Name)
+		if err != nil {
+			return nil, nil, err
+		}
+		desired, err = desiredApplication(cfg, m, app)
+		if err != nil {
+			return nil, nil, err
+		}
+		if app != nil {
+			live = *app
+		}
+	case kindBackendAPI:
+		api, err := findBackendAPI(cfg, m.Name)
+		if err != nil {
+			return nil, nil, err
+		}
+		desired, err = desiredBackendAPI(cfg, m, api)
+		if err != nil {
+			return nil, nil, err
+		}
+		if api != nil {
+			live = *api
+		}
+	case kindProxy:
+		return diffProxyFields(cfg, m)
+	case kindAPIKey, kindOAuthClient:
+		return diffKeyFields(cfg, m)
+	}
+
+	desiredFields, err := flattenFields(desired)
+	if err != nil {
+		return nil, nil, err
+	}
+	if live == nil {
+		return nil, desiredFields, nil
+	}
+	liveFields, err := flattenFields(live)
+	return liveFields, desiredFields, err
+}
+
+// diffProxyFields adds the application access of the proxy to its fields
+func diffProxyFields(cfg *apimgr.Configuration, m manifest) (map[string]string, map[string]string, error) {
+	proxy, err := findProxy(cfg, m.Name)
+	if err != nil {
+		return nil, nil, err
+	}
+	desired, err := desiredProxy(cfg, m, proxy)
+	if err != nil {
+		return nil, nil, err
+	}
+	desiredFields, err := flattenFields(desired)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, name := range m.Applications {
+		desiredFields["applications."+name] = "granted"
+	}
+	if proxy == nil {
+		// apply generates the path of a new proxy without one
+		if path, ok := desiredFields["path"]; !ok || path == `""` {
+			desiredFields["path"] = `"<generated>"`
+		}
+		// the method overrides are set once the proxy and its method ids exist
+		methods, err := flattenFields(map[string]interface{}{"methods": m.Methods})
+		if err != nil {
+			return nil, nil, err
+		}
+		for key, value := range methods {
+			desiredFields[key] = value
+		}
+		return nil, desiredFields, nil
+	}
+	liveFields, err := flattenFields(*proxy)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, name := range m.Applications {
+		appID, err := resolveApplicationID(cfg, name)
+		if err != nil {
+			return nil, nil, err
+		}
+		if isPlannedID(appID) {
+			continue
+		}
+		granted, err := hasApplicationAPIAccess(appID, proxy.Id, cfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		if granted {
+			liveFields["applications."+name] = "granted"
+		}
+	}
+	return liveFields, desiredFields, nil
+}
+
+// diffKeyFields returns the fields of the API key or OAuth client of the manifest
+func diffKeyFields(cfg *apimgr.Configuration, m manifest) (map[string]string, map[string]string, error) {
+	if m.Application == "" {
+		return nil, nil, fmt.Errorf("application is required")
+	}
+	appID, err := resolveApplicationID(cfg, m.Application)
+	if err != nil {
+		return nil, nil, err
+	}
+	var live, desired interface{}
+	if m.Kind == kindAPIKey {
+		var key *apimgr.ApiKey
+		if !isPlannedID(appID) {
+			if key, err = findAPIKey(cfg, m, appID); err != nil {
+				return nil, nil, err
+			}
+		}
+		if desired, err = desiredAPIKey(m, appID, key); err != nil {
+			return nil, nil, err
+		}
+		if key != nil {
+			claimedKeys[key.Id] = true
+			live = *key
+		}
+	} else {
+		var oauth *apimgr.OAuthClient
+		if !isPlannedID(appID) {
+			if oauth, err = findOAuthClient(cfg, m, appID); err != nil {
+				return nil, nil, err
+			}
+		}
+		if desired, err = desiredOAuthClient(m, appID, oauth); err != nil {
+			return nil, nil, err
+		}
+		if oauth != nil {
+			claimedKeys[oauth.Id] = true
+			live = *oauth
+		}
+	}
+
+	desiredFields, err := flattenFields(desired)
+	if err != nil {
+		return nil, nil, err
+	}
+	if live == nil {
+		return nil, desiredFields, nil
+	}
+	liveFields, err := flattenFields(live)
+	return liveFields, desiredFields, err
+}
+
+// flattenFields turns obj into a map of field paths to JSON encoded values
+func flattenFields(obj interface{}) (map[string]string, error) {
+	content, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	var value interface{}
+	if err = json.Unmarshal(content, &value); err != nil {
+		return nil, err
+	}
+	fields := map[string]string{}
+	if top, ok := value.(map[string]interface{}); ok {
+		for key := range ignoredFields {
+			delete(top, key)
+		}
+	}
+	flattenValue("", value, fields)
+	return fields, nil
+}
+
+func flattenValue(path string, value interface{}, fields map[string]string) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, val := range v {
+			if path == "" {
+				flattenValue(key, val, fields)
+			} else {
+				flattenValue(path+"."+key, val, fields)
+			}
+		}
+	case []interface{}:
+		for i, val := range v {
+			flattenValue(fmt.Sprintf("%s[%d]", path, i), val, fields)
+		}
+	default:
+		content, _ := json.Marshal(v)
+		fields[path] = string(content)
+	}
+}
+
+// printDiff prints a unified diff of the fields and reports whether they differ
+func printDiff(m manifest, live, desired map[string]string) bool {
+	keys := []string{}
+	for key := range desired {
+		keys = append(keys, key)
+	}
+	for key := range live {
+		if _, ok := desired[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	lines := []string{}
+	for _, key := range keys {
+		liveValue, inLive := live[key]
+		desiredValue, inDesired := desired[key]
+		if inLive && inDesired && liveValue == desiredValue {
+			continue
+		}
+		if inLive {
+			lines = append(lines, fmt.Sprintf("-%s: %s", key, maskSecret(key, liveValue)))
+		}
+		if inDesired {
+			lines = append(lines, fmt.Sprintf("+%s: %s", key, maskSecret(key, desiredValue)))
+		}
+	}
+	if len(lines) == 0 {
+		return false
+	}
+	if live == nil {
+		fmt.Printf("--- live/%v (not found)\n", m)
+	} else {
+		fmt.Printf("--- live/%v\n", m)
+	}
+	fmt.Printf("+++ manifest/%v\n", m)
+	for _, line := range lines {
+		fmt.Println(line)
+	}
+	return true
+}
+
+// maskSecret hides the value of a secret field, changes to it are still shown
+func maskSecret(path, value string) string {
+	key := path[strings.LastIndex(path, ".")+1:]
+	if !secretFields[key] || value == `""` || value == "null" {
+		return value
+	}
+	return `"***"`
+}
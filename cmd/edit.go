@@ -0,0 +1,210 @@
+/*
+Copyright © 2020 Axway, Inc. <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// fields that are managed by API Manager, the proxy state is changed with the
+// publish and unpublish commands
+var (
+	organizationImmutableFields = []string{"id", "dn", "createdOn"}
+	userImmutableFields         = []string{"id", "createdOn"}
+	applicationImmutableFields  = []string{"id", "createdOn"}
+	proxyImmutableFields        = []string{"id", "organizationId", "apiId", "state", "createdOn", "createdBy"}
+	backendAPIImmutableFields   = []string{"id", "organizationId", "createdOn", "createdBy"}
+)
+
+const editHeader = "# Please edit the %v below. Lines beginning with a '#' are ignored,\n" +
+	"# and an empty file aborts the edit.\n#\n"
+
+var editFormat string
+
+// errEditCancelled is returned by editObject when the file was saved without changes
+var errEditCancelled = errors.New("edit cancelled, no changes made")
+
+// editObject opens obj in $KUBE_EDITOR, $EDITOR or vim as json or yaml, as selected
+// with -o, and decodes the result into edited. While the result can't be decoded or
+// validate fails, the file is opened again with the error as a comment at the top.
+func editObject(kind, name string, obj, edited interface{}, validate func() error) error {
+	format := editFormat
+	if format != "json" && format != "yaml" {
+		return fmt.Errorf("unknown edit format %q, allowed formats: json|yaml", format)
+	}
+	var content []byte
+	var err error
+	if format == "yaml" {
+		content, err = toYAML(obj)
+	} else {
+		content, err = json.MarshalIndent(obj, "", "    ")
+	}
+	if err != nil {
+		return err
+	}
+
+	dir, err := ioutil.TempDir("", "apimanager-edit-")
+	if err != nil {
+		return err
+	}
+	// the file is kept when the edits can't be applied so they aren't lost
+	keep := false
+	defer func() {
+		if !keep {
+			os.RemoveAll(dir)
+		}
+	}()
+	fileName := filepath.Join(dir, exportFileName(kind+"-"+name))
+	fileName = strings.TrimSuffix(fileName, ".yaml") + "." + format
+
+	original := content
+	header := fmt.Sprintf(editHeader, kind)
+	for {
+		if err = ioutil.WriteFile(fileName, append([]byte(header), content...), 0600); err != nil {
+			return err
+		}
+		if err = runEditor(fileName); err != nil {
+			keep = true
+			return fmt.Errorf("%v, the edited file is kept in %v", err, fileName)
+		}
+		saved, err := ioutil.ReadFile(fileName)
+		if err != nil {
+			return err
+		}
+		saved = stripComments(saved)
+		if len(bytes.TrimSpace(saved)) == 0 || bytes.Equal(bytes.TrimSpace(saved), bytes.TrimSpace(original)) {
+			return errEditCancelled
+		}
+		if !bytes.Equal(content, original) && bytes.Equal(saved, content) {
+			// reopened with an error and saved without fixing it
+			keep = true
+			return fmt.Errorf("edit aborted, the %v is still invalid, your changes are kept in %v", kind, fileName)
+		}
+
+		err = decodeEdited(format, saved, edited)
+		if err == nil {
+			err = validate()
+		}
+		if err == nil {
+			return nil
+		}
+		content = saved
+		header = fmt.Sprintf(editHeader, kind) + "# " + strings.Replace(err.Error(), "\n", "\n# ", -1) + "\n#\n"
+	}
+}
+
+// runEditor opens the file in the editor of the user
+func runEditor(fileName string) error {
+	editor := os.Getenv("KUBE_EDITOR")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vim"
+	}
+	args := strings.Fields(editor)
+	cm := exec.Command(args[0], append(args[1:], fileName)...)
+	cm.Stdin = os.Stdin
+	cm.Stdout = os.Stdout
+	cm.Stderr = os.Stderr
+	if err := cm.Run(); err != nil {
+		return fmt.Errorf("failed to run editor %v: %v", editor, err)
+	}
+	return nil
+}
+
+// stripComments removes the comment lines at the top of the edited file
+func stripComments(content []byte) []byte {
+	for len(content) != 0 {
+		line := content
+		rest := []byte{}
+		if i := bytes.IndexByte(content, '\n'); i >= 0 {
+			line, rest = content[:i], content[i+1:]
+		}
+		if !bytes.HasPrefix(bytes.TrimSpace(line), []byte("#")) {
+			break
+		}
+		content = rest
+	}
+	return content
+}
+
+// decodeEdited decodes the edited json or yaml into edited, fields of a previous attempt are cleared
+func decodeEdited(format string, content []byte, edited interface{}) error {
+	value := reflect.ValueOf(edited).Elem()
+	value.Set(reflect.Zero(value.Type()))
+	if format == "yaml" {
+		var value interface{}
+		if err := yaml.Unmarshal(content, &value); err != nil {
+			return fmt.Errorf("invalid yaml: %v", err)
+		}
+		if err := fromYAMLValue(value, edited); err != nil {
+			return fmt.Errorf("invalid object: %v", err)
+		}
+		return nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	if err := decoder.Decode(edited); err != nil {
+		return fmt.Errorf("invalid json: %v", err)
+	}
+	return nil
+}
+
+// checkImmutable returns an error naming the fields that differ between the live
+// and the edited object, fields are json names
+func checkImmutable(kind string, live, edited interface{}, fields ...string) error {
+	liveFields, err := jsonFields(live)
+	if err != nil {
+		return err
+	}
+	editedFields, err := jsonFields(edited)
+	if err != nil {
+		return err
+	}
+	changed := []string{}
+	for _, field := range fields {
+		if !reflect.DeepEqual(liveFields[field], editedFields[field]) {
+			changed = append(changed, field)
+		}
+	}
+	if len(changed) != 0 {
+		return fmt.Errorf("%v can't be updated, immutable fields changed: %v", kind, strings.Join(changed, ", "))
+	}
+	return nil
+}
+
+// jsonFields returns the top level fields of obj by their json names
+func jsonFields(obj interface{}) (map[string]interface{}, error) {
+	content, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	fields := map[string]interface{}{}
+	err = json.Unmarshal(content, &fields)
+	return fields, err
+}
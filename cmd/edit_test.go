@@ -0,0 +1,68 @@
+/*
+Copyright © 2020 Axway, Inc. <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/skckadiyala/apimanager/apimgr"
+)
+
+func TestStripComments(t *testing.T) {
+	tests := []struct {
+		content, want string
+	}{
+		{"", ""},
+		{"name: a\n", "name: a\n"},
+		{"# comment\nname: a\n", "name: a\n"},
+		{"# one\n  # two\nname: a\n# kept\n", "name: a\n# kept\n"},
+		{"# only comments", ""},
+		{"# comment\n\nname: a\n", "\nname: a\n"},
+	}
+	for _, test := range tests {
+		if got := string(stripComments([]byte(test.content))); got != test.want {
+			t.Errorf("stripComments(%q) = %q, want %q", test.content, got, test.want)
+		}
+	}
+}
+
+func TestDecodeEdited(t *testing.T) {
+	tests := []struct {
+		format, content string
+		want            apimgr.Organization
+		wantErr         bool
+	}{
+		{"yaml", "name: Marvel\nenabled: true\n", apimgr.Organization{Name: "Marvel", Enabled: true}, false},
+		{"json", `{"name": "Marvel", "enabled": true}`, apimgr.Organization{Name: "Marvel", Enabled: true}, false},
+		{"yaml", "name: [Marvel\n", apimgr.Organization{}, true},
+		{"json", `{"name": `, apimgr.Organization{}, true},
+		{"yaml", "name: Disney\n", apimgr.Organization{Name: "Disney"}, false},
+	}
+	for _, test := range tests {
+		// a previous attempt is cleared before decoding
+		got := apimgr.Organization{Name: "previous", Email: "previous@marvel.com"}
+		err := decodeEdited(test.format, []byte(test.content), &got)
+		if (err != nil) != test.wantErr {
+			t.Errorf("decodeEdited(%v, %q) error = %v, want error %v", test.format, test.content, err, test.wantErr)
+			continue
+		}
+		if !test.wantErr && !reflect.DeepEqual(got, test.want) {
+			t.Errorf("decodeEdited(%v, %q) = %+v, want %+v", test.format, test.content, got, test.want)
+		}
+	}
+}
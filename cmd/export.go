@@ -0,0 +1,311 @@
+/*
+Copyright © 2020 Axway, Inc. <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/skckadiyala/apimanager/apimgr"
+	"github.com/skckadiyala/kubecrt-vms/utils"
+	"github.com/spf13/cobra"
+)
+
+var exportDir string
+
+// exportCmd represents the export command
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export all API Manager resources to manifests",
+	Long: `Export every organization, user, application, backend API and proxy to a
+directory of manifests that can be re-applied with 'apimanager apply -R'.
+
+The directory is laid out as:
+
+  organizations/<name>.yaml
+  users/<name>.yaml
+  applications/<name>.yaml    application, API keys and OAuth clients
+  apis/<name>.yaml            backend API and the original swagger
+  proxies/<name>.yaml         proxy and its application access
+
+Resources sharing a name get their version or organization added to the file
+name, e.g. proxies/<name>-<version>.yaml. The manifests hold secrets, they are
+only readable by the user.
+
+For example:
+
+  # Snapshot the instance to ./backup
+  apimanager export -d ./backup
+`,
+	Run: export,
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+
+	exportCmd.Flags().StringVarP(&exportDir, "dir", "d", "", "directory to write the manifests to")
+	exportCmd.MarkFlagRequired("dir")
+}
+
+func export(cmd *cobra.Command, args []string) {
+	cfg := getConfig()
+	client := apimgr.NewAPIClient(cfg)
+	ctx := context.Background()
+
+	orgs, _, err := client.OrganizationsApi.OrganizationsGet(ctx, &apimgr.OrganizationsGetOpts{})
+	if err != nil {
+		utils.PrettyPrintErr("Error listing the organizations: %v", err)
+		os.Exit(1)
+	}
+	names := []string{}
+	for _, org := range orgs {
+		names = append(names, org.Name)
+	}
+	fileNames, err := exportFileNames(names)
+	if err != nil {
+		utils.PrettyPrintErr("Error exporting the organizations: %v", err)
+		os.Exit(1)
+	}
+	orgNames := map[string]string{}
+	for i, org := range orgs {
+		orgNames[org.Id] = org.Name
+		err = writeManifests(filepath.Join(exportDir, "organizations", fileNames[i]), newManifest(kindOrganization, org.Name, org))
+		if err != nil {
+			utils.PrettyPrintErr("Error exporting organization %v: %v", org.Name, err)
+			os.Exit(1)
+		}
+	}
+	utils.PrettyPrintInfo("%v organizations exported", len(orgs))
+
+	users, _, err := client.UsersApi.UsersGet(ctx, &apimgr.UsersGetOpts{})
+	if err != nil {
+		utils.PrettyPrintErr("Error listing the users: %v", err)
+		os.Exit(1)
+	}
+	names, owners := []string{}, []string{}
+	for _, user := range users {
+		names, owners = append(names, user.Name), append(owners, orgNames[user.OrganizationId])
+	}
+	if fileNames, err = exportFileNames(names, owners); err != nil {
+		utils.PrettyPrintErr("Error exporting the users: %v", err)
+		os.Exit(1)
+	}
+	for i, user := range users {
+		m := newManifest(kindUser, user.Name, user)
+		m.Organization = orgNames[user.OrganizationId]
+		err = writeManifests(filepath.Join(exportDir, "users", fileNames[i]), m)
+		if err != nil {
+			utils.PrettyPrintErr("Error exporting user %v: %v", user.Name, err)
+			os.Exit(1)
+		}
+	}
+	utils.PrettyPrintInfo("%v users exported", len(users))
+
+	// application access is exported with the proxies, they are applied after the applications
+	access := map[string][]string{}
+	apps, _, err := client.ApplicationsApi.ApplicationsGet(ctx, &apimgr.ApplicationsGetOpts{})
+	if err != nil {
+		utils.PrettyPrintErr("Error listing the applications: %v", err)
+		os.Exit(1)
+	}
+	names, owners = []string{}, []string{}
+	for _, app := range apps {
+		names, owners = append(names, app.Name), append(owners, orgNames[app.OrganizationId])
+	}
+	if fileNames, err = exportFileNames(names, owners); err != nil {
+		utils.PrettyPrintErr("Error exporting the applications: %v", err)
+		os.Exit(1)
+	}
+	for i, app := range apps {
+		m := newManifest(kindApplication, app.Name, app)
+		m.Organization = orgNames[app.OrganizationId]
+		manifests := []manifest{m}
+
+		apis, _, err := client.ApplicationsApi.ApplicationsIdApisGet(ctx, app.Id)
+		if err != nil {
+			utils.PrettyPrintErr("Error listing the api access of %v: %v", app.Name, err)
+			os.Exit(1)
+		}
+		for _, api := range apis {
+			access[api.ApiId] = append(access[api.ApiId], app.Name)
+		}
+		keys, _, err := client.ApplicationsApi.ApplicationsIdApikeysGet(ctx, app.Id)
+		if err != nil {
+			utils.PrettyPrintErr("Error listing the apiKeys of %v: %v", app.Name, err)
+			os.Exit(1)
+		}
+		for _, key := range keys {
+			km := newManifest(kindAPIKey, key.Id, key)
+			km.Application = app.Name
+			manifests = append(manifests, km)
+		}
+		oauths, _, err := client.ApplicationsApi.ApplicationsIdOauthGet(ctx, app.Id)
+		if err != nil {
+			utils.PrettyPrintErr("Error listing the oauth of %v: %v", app.Name, err)
+			os.Exit(1)
+		}
+		for _, oauth := range oauths {
+			om := newManifest(kindOAuthClient, oauth.Id, oauth)
+			om.Application = app.Name
+			manifests = append(manifests, om)
+		}
+		err = writeManifests(filepath.Join(exportDir, "applications", fileNames[i]), manifests...)
+		if err != nil {
+			utils.PrettyPrintErr("Error exporting application %v: %v", app.Name, err)
+			os.Exit(1)
+		}
+	}
+	utils.PrettyPrintInfo("%v applications exported", len(apps))
+
+	apis, _, err := client.APIRepositoryApi.ApirepoGet(ctx, &apimgr.ApirepoGetOpts{})
+	if err != nil {
+		utils.PrettyPrintErr("Error listing the backend APIs: %v", err)
+		os.Exit(1)
+	}
+	names, owners = []string{}, []string{}
+	for _, api := range apis {
+		names, owners = append(names, api.Name), append(owners, orgNames[api.OrganizationId])
+	}
+	if fileNames, err = exportFileNames(names, owners); err != nil {
+		utils.PrettyPrintErr("Error exporting the backend APIs: %v", err)
+		os.Exit(1)
+	}
+	apiNames := map[string]string{}
+	for i, api := range apis {
+		apiNames[api.Id] = api.Name
+		m := newManifest(kindBackendAPI, api.Name, api)
+		m.Organization = orgNames[api.OrganizationId]
+		m.Swagger = strings.TrimSuffix(fileNames[i], ".yaml") + ".swagger.json"
+
+		swagger, err := apiRequest(cfg, "GET", "/apirepo/"+api.Id+"/download?original=true", nil, "")
+		if err != nil {
+			utils.PrettyPrintErr("Error downloading the swagger of %v: %v", api.Name, err)
+			os.Exit(1)
+		}
+		if err = os.MkdirAll(filepath.Join(exportDir, "apis"), 0700); err != nil {
+			utils.PrettyPrintErr("Error exporting backend API %v: %v", api.Name, err)
+			os.Exit(1)
+		}
+		err = ioutil.WriteFile(filepath.Join(exportDir, "apis", m.Swagger), swagger, 0600)
+		if err == nil {
+			err = writeManifests(filepath.Join(exportDir, "apis", fileNames[i]), m)
+		}
+		if err != nil {
+			utils.PrettyPrintErr("Error exporting backend API %v: %v", api.Name, err)
+			os.Exit(1)
+		}
+	}
+	utils.PrettyPrintInfo("%v backend APIs exported", len(apis))
+
+	proxies, _, err := client.APIProxyRegistrationApi.ProxiesGet(ctx, &apimgr.ProxiesGetOpts{})
+	if err != nil {
+		utils.PrettyPrintErr("Error listing the proxies: %v", err)
+		os.Exit(1)
+	}
+	names, owners = []string{}, []string{}
+	versions := []string{}
+	for _, proxy := range proxies {
+		names, owners = append(names, proxy.Name), append(owners, orgNames[proxy.OrganizationId])
+		versions = append(versions, proxy.Version)
+	}
+	if fileNames, err = exportFileNames(names, versions, owners); err != nil {
+		utils.PrettyPrintErr("Error exporting the proxies: %v", err)
+		os.Exit(1)
+	}
+	for i, proxy := range proxies {
+		proxy, _, err = client.APIProxyRegistrationApi.ProxiesIdGet(ctx, proxy.Id)
+		if err != nil {
+			utils.PrettyPrintErr("Unable to get the Proxy: %v", err)
+			os.Exit(1)
+		}
+		m := newManifest(kindProxy, proxy.Name, proxy)
+		m.Organization = orgNames[proxy.OrganizationId]
+		m.BackendAPI = apiNames[proxy.ApiId]
+		m.Applications = access[proxy.Id]
+		err = writeManifests(filepath.Join(exportDir, "proxies", fileNames[i]), m)
+		if err != nil {
+			utils.PrettyPrintErr("Error exporting proxy %v: %v", proxy.Name, err)
+			os.Exit(1)
+		}
+	}
+	utils.PrettyPrintInfo("%v proxies exported", len(proxies))
+}
+
+// newManifest returns a manifest with obj as its spec
+func newManifest(kind, name string, obj interface{}) manifest {
+	spec, _ := json.Marshal(obj)
+	return manifest{Kind: kind, Name: name, Spec: spec}
+}
+
+// exportFileName turns a resource name into a file name
+func exportFileName(name string) string {
+	replacer := strings.NewReplacer("/", "_", "\\", "_", ":", "_", "*", "_", "?", "_", "\"", "_", "<", "_", ">", "_", "|", "_")
+	return replacer.Replace(name) + ".yaml"
+}
+
+// exportFileNames returns the file names of resources, the qualifiers of a resource,
+// e.g. its version or organization, are added in turn to the names several resources
+// share. It fails when the file names are still not unique.
+func exportFileNames(names []string, qualifiers ...[]string) ([]string, error) {
+	fileNames := append([]string{}, names...)
+	for _, qualifier := range qualifiers {
+		counts := map[string]int{}
+		for _, name := range fileNames {
+			counts[strings.ToLower(exportFileName(name))]++
+		}
+		for i, name := range fileNames {
+			if counts[strings.ToLower(exportFileName(name))] > 1 && qualifier[i] != "" {
+				fileNames[i] = name + "-" + qualifier[i]
+			}
+		}
+	}
+	exported := map[string]string{}
+	for i, name := range fileNames {
+		fileNames[i] = exportFileName(name)
+		key := strings.ToLower(fileNames[i])
+		if other, ok := exported[key]; ok {
+			return nil, fmt.Errorf("%v and %v would both be exported to %v", other, names[i], fileNames[i])
+		}
+		exported[key] = names[i]
+	}
+	return fileNames, nil
+}
+
+// writeManifests writes the manifests as YAML documents to the file
+func writeManifests(fileName string, manifests ...manifest) error {
+	out := bytes.Buffer{}
+	for i, m := range manifests {
+		if i != 0 {
+			out.WriteString("---\n")
+		}
+		content, err := toYAML(m)
+		if err != nil {
+			return err
+		}
+		out.Write(content)
+	}
+	if err := os.MkdirAll(filepath.Dir(fileName), 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fileName, out.Bytes(), 0600)
+}
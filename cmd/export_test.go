@@ -0,0 +1,53 @@
+/*
+Copyright © 2020 Axway, Inc. <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExportFileNames(t *testing.T) {
+	tests := []struct {
+		name       string
+		names      []string
+		qualifiers [][]string
+		want       []string
+		wantErr    bool
+	}{
+		{"unique", []string{"Iron Man", "Thor"}, nil, []string{"Iron Man.yaml", "Thor.yaml"}, false},
+		{"unsafe characters", []string{"a/b:c?"}, nil, []string{"a_b_c_.yaml"}, false},
+		{"owner", []string{"Avengers", "Avengers", "Thor"}, [][]string{{"Marvel", "Disney", "Marvel"}},
+			[]string{"Avengers-Marvel.yaml", "Avengers-Disney.yaml", "Thor.yaml"}, false},
+		{"case", []string{"avengers", "Avengers"}, [][]string{{"Marvel", "Disney"}},
+			[]string{"avengers-Marvel.yaml", "Avengers-Disney.yaml"}, false},
+		{"version then owner", []string{"A", "A", "B", "B"}, [][]string{{"1", "2", "1", "1"}, {"x", "x", "x", "y"}},
+			[]string{"A-1.yaml", "A-2.yaml", "B-1-x.yaml", "B-1-y.yaml"}, false},
+		{"same qualifiers", []string{"Avengers", "Avengers"}, [][]string{{"Marvel", "Marvel"}}, nil, true},
+		{"no qualifier", []string{"Avengers", "avengers"}, nil, nil, true},
+	}
+	for _, test := range tests {
+		got, err := exportFileNames(test.names, test.qualifiers...)
+		if (err != nil) != test.wantErr {
+			t.Errorf("%v: error = %v, want error %v", test.name, err, test.wantErr)
+			continue
+		}
+		if !test.wantErr && !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%v: file names = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
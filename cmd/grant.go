@@ -0,0 +1,238 @@
+/*
+Copyright © 2020 Axway, Inc. <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/skckadiyala/apimanager/apimgr"
+	"github.com/skckadiyala/kubecrt-vms/utils"
+	"github.com/spf13/cobra"
+)
+
+var (
+	grantOrgs []string
+	allOrgs   bool
+)
+
+// grantCmd represents the grant command
+var (
+	grantCmd = &cobra.Command{
+		Use:   "grant",
+		Short: "Grant access to an API Manager resource",
+	}
+	proxyGrant = &cobra.Command{
+		Use:   "proxy",
+		Short: "Grant organizations access to a proxy",
+		Long: `Grant organizations access to a published proxy, the applications of an
+organization can only subscribe to the proxies it was granted.
+
+For example:
+
+# Grant two organizations access to a proxy
+apimanager grant proxy -n <ProxyName> --org <orgName> --org <otherOrgName>
+
+# Grant all organizations access to a proxy
+apimanager grant proxy -n <ProxyName> --all-orgs`,
+		Run: grantProxy,
+	}
+	revokeCmd = &cobra.Command{
+		Use:   "revoke",
+		Short: "Revoke access to an API Manager resource",
+	}
+	proxyRevoke = &cobra.Command{
+		Use:   "proxy",
+		Short: "Revoke the access of organizations to a proxy",
+		Long: `Revoke the access of organizations to a proxy, the organization owning the
+proxy keeps its access.
+
+For example:
+
+apimanager revoke proxy -n <ProxyName> --org <orgName>
+apimanager revoke proxy -n <ProxyName> --all-orgs`,
+		Run: revokeProxy,
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(grantCmd)
+	rootCmd.AddCommand(revokeCmd)
+	grantCmd.AddCommand(proxyGrant)
+	revokeCmd.AddCommand(proxyRevoke)
+
+	for _, cmd := range []*cobra.Command{proxyGrant, proxyRevoke} {
+		cmd.Flags().StringVarP(&name, "name", "n", "", "proxy name")
+		cmd.Flags().StringVarP(&lifecycleVersion, "proxyVersion", "v", "", "version of the proxy when several have the name")
+		cmd.Flags().StringArrayVar(&grantOrgs, "org", nil, "organization name, repeatable")
+		cmd.Flags().BoolVar(&allOrgs, "all-orgs", false, "all organizations")
+		cmd.MarkFlagRequired("name")
+	}
+}
+
+func grantProxy(cmd *cobra.Command, args []string) {
+	cfg := getConfig()
+
+	proxy, orgIDs := proxyAndOrgs(cfg)
+	form := url.Values{}
+	form.Set("apiId", proxy.Id)
+	if allOrgs {
+		form.Set("action", "all_orgs")
+	} else {
+		form.Set("action", "org_apis")
+		for _, orgID := range orgIDs {
+			form.Add("grantOrgId", orgID)
+		}
+	}
+	_, err := apiRequest(cfg, "POST", "/proxies/grantaccess", strings.NewReader(form.Encode()), "application/x-www-form-urlencoded")
+	if err != nil {
+		utils.PrettyPrintErr("Error granting access to Proxy %v: %v", proxy.Name, err)
+		os.Exit(1)
+	}
+	if allOrgs {
+		utils.PrettyPrintInfo("All organizations granted access to Proxy %v", proxy.Name)
+		return
+	}
+	utils.PrettyPrintInfo("%v granted access to Proxy %v", strings.Join(grantOrgs, ", "), proxy.Name)
+}
+
+func revokeProxy(cmd *cobra.Command, args []string) {
+	cfg := getConfig()
+	client := apimgr.NewAPIClient(cfg)
+
+	proxy, orgIDs := proxyAndOrgs(cfg)
+	if allOrgs {
+		orgs, _, err := client.OrganizationsApi.OrganizationsGet(context.Background(), &apimgr.OrganizationsGetOpts{})
+		if err != nil {
+			utils.PrettyPrintErr("Error listing the organizations: %v", err)
+			os.Exit(1)
+		}
+		for _, org := range orgs {
+			if org.Id != proxy.OrganizationId {
+				orgIDs = append(orgIDs, org.Id)
+			}
+		}
+	}
+
+	orgNameOf := organizationNames(cfg)
+	revoked := 0
+	for _, orgID := range orgIDs {
+		if orgID == proxy.OrganizationId {
+			utils.PrettyPrintErr("%v owns Proxy %v, its access can't be revoked", orgNameOf(orgID), proxy.Name)
+			os.Exit(1)
+		}
+		access, err := organizationAPIAccess(cfg, orgID)
+		if err != nil {
+			utils.PrettyPrintErr("Error listing the api access of %v: %v", orgNameOf(orgID), err)
+			os.Exit(1)
+		}
+		for _, a := range access {
+			if a.ApiId != proxy.Id {
+				continue
+			}
+			if _, err = apiRequest(cfg, "DELETE", "/organizations/"+orgID+"/apis/"+a.Id, nil, ""); err != nil {
+				utils.PrettyPrintErr("Error revoking the access of %v: %v", orgNameOf(orgID), err)
+				os.Exit(1)
+			}
+			utils.PrettyPrintInfo("Access of %v to Proxy %v revoked", orgNameOf(orgID), proxy.Name)
+			revoked++
+		}
+	}
+	if revoked == 0 {
+		utils.PrettyPrintInfo("No organization had access to Proxy %v", proxy.Name)
+	}
+}
+
+// proxyAndOrgs returns the proxy given by -n and the ids of the organizations given by --org
+func proxyAndOrgs(cfg *apimgr.Configuration) (apimgr.VirtualizedApi, []string) {
+	if allOrgs == (len(grantOrgs) != 0) {
+		utils.PrettyPrintErr("Either --org or --all-orgs is required")
+		os.Exit(1)
+	}
+	proxy, err := findProxyVersion(cfg, name, lifecycleVersion)
+	if err != nil {
+		utils.PrettyPrintErr("%v", err)
+		os.Exit(1)
+	}
+	orgIDs := []string{}
+	for _, org := range grantOrgs {
+		orgID, err := resolveOrganizationID(cfg, org)
+		if err != nil {
+			utils.PrettyPrintErr("%v", err)
+			os.Exit(1)
+		}
+		orgIDs = append(orgIDs, orgID)
+	}
+	return proxy, orgIDs
+}
+
+// organizationAPIAccess returns the proxies the organization was granted
+func organizationAPIAccess(cfg *apimgr.Configuration, orgID string) ([]apimgr.ApiAccess, error) {
+	access := []apimgr.ApiAccess{}
+	content, err := apiRequest(cfg, "GET", "/organizations/"+orgID+"/apis", nil, "")
+	if err == nil {
+		err = json.Unmarshal(content, &access)
+	}
+	return access, err
+}
+
+// organizationAPI is a proxy an organization can see, as owner or by a grant
+type organizationAPI struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	ProxyID string `json:"proxyId"`
+	Access  string `json:"access"` // owner or granted
+	Enabled bool   `json:"enabled"`
+	State   string `json:"state"`
+}
+
+// organizationAPIs returns the proxies the organization owns or was granted
+func organizationAPIs(cfg *apimgr.Configuration, orgID string) ([]organizationAPI, error) {
+	client := apimgr.NewAPIClient(cfg)
+
+	proxies, _, err := client.APIProxyRegistrationApi.ProxiesGet(context.Background(), &apimgr.ProxiesGetOpts{})
+	if err != nil {
+		return nil, err
+	}
+	byID := map[string]apimgr.VirtualizedApi{}
+	apis := []organizationAPI{}
+	for _, proxy := range proxies {
+		byID[proxy.Id] = proxy
+		if proxy.OrganizationId == orgID {
+			apis = append(apis, organizationAPI{Name: proxy.Name, Version: proxy.Version, ProxyID: proxy.Id, Access: "owner", Enabled: true, State: proxy.State})
+		}
+	}
+	access, err := organizationAPIAccess(cfg, orgID)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range access {
+		proxy, ok := byID[a.ApiId]
+		if !ok {
+			proxy.Name = fmt.Sprintf("<unknown %v>", a.ApiId)
+		}
+		if proxy.OrganizationId == orgID {
+			continue
+		}
+		apis = append(apis, organizationAPI{Name: proxy.Name, Version: proxy.Version, ProxyID: a.ApiId, Access: "granted", Enabled: a.Enabled, State: proxy.State})
+	}
+	return apis, nil
+}
@@ -0,0 +1,295 @@
+/*
+Copyright © 2020 Axway, Inc. <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/skckadiyala/apimanager/apimgr"
+	"github.com/skckadiyala/kubecrt-vms/utils"
+	"github.com/spf13/cobra"
+)
+
+var importDir string
+
+// importCmd represents the import command
+var importCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Import an export into an API Manager instance",
+	Long: `Import a directory written by 'apimanager export' into the API Manager instance
+of the context given by --context. Server generated IDs are remapped to the IDs of the
+target instance and everything that could not be migrated is reported.
+
+For example:
+
+  # Copy the dev instance to staging
+  apimanager export -d ./dev --context dev
+  apimanager import --from ./dev --context staging
+`,
+	Run: importInstance,
+}
+
+func init() {
+	rootCmd.AddCommand(importCmd)
+
+	importCmd.Flags().StringVar(&importDir, "from", "", "directory written by export")
+	importCmd.MarkFlagRequired("from")
+}
+
+func importInstance(cmd *cobra.Command, args []string) {
+	manifests, err := loadManifests([]string{importDir}, true)
+	if err != nil {
+		utils.PrettyPrintErr("Error reading the export: %v", err)
+		os.Exit(1)
+	}
+	sortManifests(manifests)
+
+	cfg := getConfig()
+	ids := map[string]string{}
+	stdout := fmtDisplay()
+	fmt.Fprintf(stdout, "KIND\tNAME\tACTION\tOLD ID\tNEW ID\n")
+	notMigrated, failed := []string{}, []string{}
+
+	for _, m := range manifests {
+		oldID := specID(m)
+		m.Spec = remapIDs(m.Spec, ids)
+		if m.Kind == kindProxy {
+			var dropped []string
+			m, dropped = dropMethodProfiles(m)
+			for _, method := range dropped {
+				notMigrated = append(notMigrated, fmt.Sprintf("%v: method profile %v", m, method))
+			}
+		}
+
+		var existing []string
+		var err error
+		if m.Kind == kindAPIKey || m.Kind == kindOAuthClient {
+			if existing, err = applicationKeyIDs(cfg, m); err != nil {
+				failed = append(failed, fmt.Sprintf("%v: %v", m, err))
+				continue
+			}
+		}
+		action, err := applyManifest(cfg, m)
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%v: %v", m, err))
+			continue
+		}
+		newID, err := importedID(cfg, m, existing)
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%v: %v", m, err))
+			continue
+		}
+		if oldID != "" && newID != "" {
+			ids[oldID] = newID
+		}
+		fmt.Fprintf(stdout, "%v\t%v\t%v\t%v\t%v\n", m.Kind, m.Name, action, oldID, newID)
+
+		switch m.Kind {
+		case kindUser:
+			if action == "created" && m.Password == "" {
+				notMigrated = append(notMigrated, fmt.Sprintf("%v: password", m))
+			}
+		case kindAPIKey, kindOAuthClient:
+			if m.Name != "" && newID != m.Name {
+				notMigrated = append(notMigrated, fmt.Sprintf("%v: key id, a new key %v was created", m, newID))
+			} else if action == "created" && specSecret(m) != "" && specSecret(m) != importedSecret(cfg, m) {
+				notMigrated = append(notMigrated, fmt.Sprintf("%v: secret, a new secret was generated", m))
+			}
+		}
+	}
+	stdout.Flush()
+
+	if len(notMigrated) != 0 {
+		fmt.Println("\nNot migrated:")
+		for _, item := range notMigrated {
+			fmt.Printf("  %v\n", item)
+		}
+	}
+	if len(failed) != 0 {
+		fmt.Println("\nFailed:")
+		for _, item := range failed {
+			fmt.Printf("  %v\n", item)
+		}
+		os.Exit(1)
+	}
+}
+
+// specID returns the ID the resource had on the exported instance
+func specID(m manifest) string {
+	spec := struct {
+		Id string `json:"id"`
+	}{}
+	json.Unmarshal(m.Spec, &spec)
+	return spec.Id
+}
+
+func specSecret(m manifest) string {
+	spec := struct {
+		Secret string `json:"secret"`
+	}{}
+	json.Unmarshal(m.Spec, &spec)
+	return spec.Secret
+}
+
+// remapIDs replaces the exported IDs in the spec with the IDs on the target instance
+func remapIDs(spec json.RawMessage, ids map[string]string) json.RawMessage {
+	if len(spec) == 0 {
+		return spec
+	}
+	pairs := []string{}
+	for oldID, newID := range ids {
+		pairs = append(pairs, `"`+oldID+`"`, `"`+newID+`"`)
+	}
+	return json.RawMessage(strings.NewReplacer(pairs...).Replace(string(spec)))
+}
+
+// dropMethodProfiles removes the per method profiles of a proxy, they refer to
+// method IDs of the exported instance
+func dropMethodProfiles(m manifest) (manifest, []string) {
+	proxy := apimgr.VirtualizedApi{}
+	if err := json.Unmarshal(m.Spec, &proxy); err != nil {
+		return m, nil
+	}
+	dropped := []string{}
+	for method := range proxy.InboundProfiles {
+		if method != "_default" {
+			delete(proxy.InboundProfiles, method)
+			dropped = append(dropped, "inbound "+method)
+		}
+	}
+	for method := range proxy.OutboundProfiles {
+		if method != "_default" {
+			delete(proxy.OutboundProfiles, method)
+			dropped = append(dropped, "outbound "+method)
+		}
+	}
+	if len(dropped) != 0 {
+		m.Spec, _ = json.Marshal(proxy)
+	}
+	return m, dropped
+}
+
+// importedID returns the ID of the applied resource on the target instance. The keys
+// of the application before the apply are given as existing, a key that kept its id
+// is found by it, otherwise by being the only key the apply added.
+func importedID(cfg *apimgr.Configuration, m manifest, existing []string) (string, error) {
+	switch m.Kind {
+	case kindOrganization:
+		return resolveOrganizationID(cfg, m.Name)
+	case kindUser:
+		user, err := findUser(cfg, m.Name)
+		if err != nil || user == nil {
+			return "", err
+		}
+		return user.Id, nil
+	case kindApplication:
+		return resolveApplicationID(cfg, m.Name)
+	case kindBackendAPI:
+		return resolveBackendAPIID(cfg, m.Name)
+	case kindProxy:
+		proxy, err := findProxy(cfg, m.Name)
+		if err != nil || proxy == nil {
+			return "", err
+		}
+		return proxy.Id, nil
+	case kindAPIKey, kindOAuthClient:
+		ids, err := applicationKeyIDs(cfg, m)
+		if err != nil {
+			return "", err
+		}
+		before := map[string]bool{}
+		for _, id := range existing {
+			before[id] = true
+		}
+		added := []string{}
+		for _, id := range ids {
+			if m.Name != "" && id == m.Name {
+				return id, nil
+			}
+			if !before[id] {
+				added = append(added, id)
+			}
+		}
+		switch {
+		case len(added) == 1:
+			return added[0], nil
+		case len(added) > 1:
+			return "", fmt.Errorf("unable to tell the new key among %v", strings.Join(added, ", "))
+		case m.Name != "":
+			return "", fmt.Errorf("no key %v or new key found on application %v", m.Name, m.Application)
+		}
+	}
+	return "", nil
+}
+
+// applicationKeyIDs returns the ids of the API keys or OAuth clients, by the kind of
+// the manifest, of its application
+func applicationKeyIDs(cfg *apimgr.Configuration, m manifest) ([]string, error) {
+	client := apimgr.NewAPIClient(cfg)
+	appID, err := resolveApplicationID(cfg, m.Application)
+	if err != nil {
+		return nil, err
+	}
+	ids := []string{}
+	if m.Kind == kindAPIKey {
+		keys, _, err := client.ApplicationsApi.ApplicationsIdApikeysGet(context.Background(), appID)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range keys {
+			ids = append(ids, key.Id)
+		}
+		return ids, nil
+	}
+	oauths, _, err := client.ApplicationsApi.ApplicationsIdOauthGet(context.Background(), appID)
+	if err != nil {
+		return nil, err
+	}
+	for _, oauth := range oauths {
+		ids = append(ids, oauth.Id)
+	}
+	return ids, nil
+}
+
+// importedSecret returns the secret of the key on the target instance
+func importedSecret(cfg *apimgr.Configuration, m manifest) string {
+	client := apimgr.NewAPIClient(cfg)
+	appID, err := resolveApplicationID(cfg, m.Application)
+	if err != nil {
+		return ""
+	}
+	if m.Kind == kindAPIKey {
+		keys, _, _ := client.ApplicationsApi.ApplicationsIdApikeysGet(context.Background(), appID)
+		for _, key := range keys {
+			if key.Id == m.Name {
+				return key.Secret
+			}
+		}
+		return ""
+	}
+	oauths, _, _ := client.ApplicationsApi.ApplicationsIdOauthGet(context.Background(), appID)
+	for _, oauth := range oauths {
+		if oauth.Id == m.Name {
+			return oauth.Secret
+		}
+	}
+	return ""
+}
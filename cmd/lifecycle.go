@@ -0,0 +1,212 @@
+/*
+Copyright © 2020 Axway, Inc. <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/skckadiyala/apimanager/apimgr"
+	"github.com/skckadiyala/kubecrt-vms/utils"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// lifecycleVersion selects the proxy version for the lifecycle, grant and access commands
+	lifecycleVersion string
+	// forceDelete deletes a deprecated proxy that applications still use
+	forceDelete bool
+)
+
+// deprecateCmd represents the deprecate command
+var (
+	deprecateCmd = &cobra.Command{
+		Use:   "deprecate",
+		Short: "Deprecate an API Manager resource",
+	}
+	proxyDeprecate = &cobra.Command{
+		Use:   "proxy",
+		Short: "Deprecate a proxy",
+		Long: `Deprecate a proxy, the API Catalog marks it deprecated. With --retire-date
+the proxy is retired on that date, applications can't call a retired proxy.
+
+For example:
+
+# Deprecate version 1.0 of a proxy and retire it at the end of the year
+apimanager deprecate proxy -n <ProxyName> -v 1.0 --retire-date 2026-12-31`,
+		Run: deprecateProxyCmd,
+	}
+	undeprecateCmd = &cobra.Command{
+		Use:   "undeprecate",
+		Short: "Undeprecate an API Manager resource",
+	}
+	proxyUndeprecate = &cobra.Command{
+		Use:   "proxy",
+		Short: "Undeprecate a proxy",
+		Long: `Undeprecate a proxy, its retirement date is removed.
+
+For example:
+
+apimanager undeprecate proxy -n <ProxyName> -v 1.0`,
+		Run: undeprecateProxyCmd,
+	}
+	retireCmd = &cobra.Command{
+		Use:   "retire",
+		Short: "Retire an API Manager resource",
+	}
+	proxyRetire = &cobra.Command{
+		Use:   "proxy",
+		Short: "Retire a proxy now",
+		Long: `Retire a proxy now, applications can't call it anymore. Use deprecate proxy
+--retire-date to retire a proxy later.
+
+For example:
+
+apimanager retire proxy -n <ProxyName> -v 1.0`,
+		Run: retireProxyCmd,
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(deprecateCmd)
+	rootCmd.AddCommand(undeprecateCmd)
+	rootCmd.AddCommand(retireCmd)
+	deprecateCmd.AddCommand(proxyDeprecate)
+	undeprecateCmd.AddCommand(proxyUndeprecate)
+	retireCmd.AddCommand(proxyRetire)
+
+	for _, cmd := range []*cobra.Command{proxyDeprecate, proxyUndeprecate, proxyRetire} {
+		cmd.Flags().StringVarP(&name, "name", "n", "", "proxy name")
+		cmd.Flags().StringVarP(&lifecycleVersion, "proxyVersion", "v", "", "version of the proxy when several have the name")
+		cmd.MarkFlagRequired("name")
+	}
+	proxyDeprecate.Flags().StringVar(&retireDate, "retire-date", "", "retirement date of the proxy, YYYY-MM-DD")
+}
+
+func deprecateProxyCmd(cmd *cobra.Command, args []string) {
+	cfg := getConfig()
+
+	retirement, err := parseRetireDate(retireDate)
+	if err != nil {
+		utils.PrettyPrintErr("%v", err)
+		os.Exit(1)
+	}
+	proxy, err := findProxyVersion(cfg, name, lifecycleVersion)
+	if err != nil {
+		utils.PrettyPrintErr("%v", err)
+		os.Exit(1)
+	}
+	if proxy.Retired {
+		utils.PrettyPrintErr("Proxy %v version %v is retired", proxy.Name, proxy.Version)
+		os.Exit(1)
+	}
+	if err = deprecateProxy(cfg, proxy.Id, retirement); err != nil {
+		utils.PrettyPrintErr("Error deprecating Proxy %v: %v", proxy.Name, err)
+		os.Exit(1)
+	}
+	if retirement.IsZero() {
+		utils.PrettyPrintInfo("Proxy %v version %v deprecated", proxy.Name, proxy.Version)
+		return
+	}
+	utils.PrettyPrintInfo("Proxy %v version %v deprecated, retiring on %v", proxy.Name, proxy.Version, retirement.Format("2006-01-02"))
+}
+
+func undeprecateProxyCmd(cmd *cobra.Command, args []string) {
+	cfg := getConfig()
+
+	proxy, err := findProxyVersion(cfg, name, lifecycleVersion)
+	if err != nil {
+		utils.PrettyPrintErr("%v", err)
+		os.Exit(1)
+	}
+	if proxy.Retired {
+		utils.PrettyPrintErr("Proxy %v version %v is retired", proxy.Name, proxy.Version)
+		os.Exit(1)
+	}
+	if !proxy.Deprecated {
+		utils.PrettyPrintInfo("Proxy %v version %v isn't deprecated", proxy.Name, proxy.Version)
+		return
+	}
+	_, err = apiRequest(cfg, "POST", "/proxies/"+proxy.Id+"/undeprecate", nil, "")
+	if err != nil {
+		utils.PrettyPrintErr("Error undeprecating Proxy %v: %v", proxy.Name, err)
+		os.Exit(1)
+	}
+	utils.PrettyPrintInfo("Proxy %v version %v undeprecated", proxy.Name, proxy.Version)
+}
+
+func retireProxyCmd(cmd *cobra.Command, args []string) {
+	cfg := getConfig()
+
+	proxy, err := findProxyVersion(cfg, name, lifecycleVersion)
+	if err != nil {
+		utils.PrettyPrintErr("%v", err)
+		os.Exit(1)
+	}
+	if proxy.Retired {
+		utils.PrettyPrintInfo("Proxy %v version %v is already retired", proxy.Name, proxy.Version)
+		return
+	}
+	// a proxy is retired by deprecating it with a retirement date that has come
+	if err = deprecateProxy(cfg, proxy.Id, time.Now()); err != nil {
+		utils.PrettyPrintErr("Error retiring Proxy %v: %v", proxy.Name, err)
+		os.Exit(1)
+	}
+	utils.PrettyPrintInfo("Proxy %v version %v retired", proxy.Name, proxy.Version)
+}
+
+// proxyLifecycle returns active, deprecated with the retirement date if there is one, or retired
+func proxyLifecycle(proxy apimgr.VirtualizedApi) string {
+	switch {
+	case proxy.Retired:
+		return "retired"
+	case proxy.Deprecated && proxy.RetirementDate != 0:
+		return "deprecated, retires " + time.Unix(0, proxy.RetirementDate*int64(time.Millisecond)).Format("2006-01-02")
+	case proxy.Deprecated:
+		return "deprecated"
+	}
+	return "active"
+}
+
+// deprecateProxy deprecates the proxy, it is retired on the retirement date when one is given
+func deprecateProxy(cfg *apimgr.Configuration, id string, retirement time.Time) error {
+	form := url.Values{}
+	if !retirement.IsZero() {
+		form.Set("retirementDate", formatRetirementDate(retirement))
+	}
+	_, err := apiRequest(cfg, "POST", "/proxies/"+id+"/deprecate", strings.NewReader(form.Encode()), "application/x-www-form-urlencoded")
+	return err
+}
+
+// parseRetireDate parses a YYYY-MM-DD date, an empty date is the zero time
+func parseRetireDate(date string) (time.Time, error) {
+	if date == "" {
+		return time.Time{}, nil
+	}
+	retirement, err := time.ParseInLocation("2006-01-02", date, time.Local)
+	if err != nil {
+		return retirement, fmt.Errorf("invalid retirement date %q, use YYYY-MM-DD", date)
+	}
+	return retirement, nil
+}
+
+func formatRetirementDate(retirement time.Time) string {
+	return retirement.UTC().Format("2006-01-02T15:04:05.000Z")
+}
@@ -21,10 +21,13 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/c-bata/go-prompt"
+	"github.com/skckadiyala/kubecrt-vms/utils"
 	"github.com/spf13/cobra"
-	"gopkg.in/yaml.v2"
+	"golang.org/x/term"
 )
 
 // createCmd represents the create command
@@ -37,10 +40,67 @@ var (
 	Port, 
 	Username 
 	Password
+
+The login info is stored in the context given by --context, the context
+becomes the current context. The hostname and port are written to
+$HOME/.apimanager.yaml, the credentials are kept encrypted in
+$HOME/.apimanager/credentials. Set APIMANAGER_CREDENTIALS_KEY to derive the
+encryption key from a passphrase instead of the generated key file, or use
+--credential-helper to keep them in an external secret store.
+
+A credential helper is called with get, store or erase as its last argument:
+get and erase read the context name on stdin and get writes the credentials
+to stdout, store reads {"context": "<name>", "secret": "<credentials>"}.
+
+Values not given by the flags are read from the APIMANAGER_HOST,
+APIMANAGER_PORT, APIMANAGER_USERNAME and APIMANAGER_PASSWORD environment
+variables, and prompted for when run in a terminal. The password is not echoed.
+The credentials are verified against API Manager before they are stored.
+
+With --auth-mode session the CLI logs in once and sends the session cookie
+and CSRF token instead of Basic credentials, the session is cached with the
+credentials and renewed when it expires. With auto, Basic credentials are used
+when the session login is rejected.
+
+The API Manager certificate is verified against the system CA pool and the
+certificates of --ca-file. TLS settings are stored with the context and kept
+by later logins unless they are given again.
+
+For example:
+
+  # Login to the dev instance
+  apimanager login --context dev
+
+  # Login to an instance behind an internal CA with mutual TLS
+  apimanager login --context prod --ca-file ca.pem --client-cert cli.pem --client-key cli-key.pem
+
+  # Login to an instance with Basic authentication disabled
+  apimanager login --context ldap --auth-mode session
+
+  # Login from a CI job
+  echo "$API_PASSWORD" | apimanager login --host apimgr.example.com --port 8075 --username apiadmin --password-stdin
+
+  # Keep the prod credentials in a secret manager
+  apimanager login --context prod --credential-helper apimanager-credential-vault
 `,
 		Run: login,
 	}
 
+	logoutCmd = &cobra.Command{
+		Use:   "logout",
+		Short: "Removes the stored credentials of API Manager",
+		Long: `Removes the credentials of the context given by --context or the current
+context. The hostname and port are kept, use 'login' to store new credentials.
+
+For example:
+
+  # Logout from the dev instance
+  apimanager logout --context dev
+`,
+		Args: cobra.NoArgs,
+		Run:  logout,
+	}
+
 	createCmd = &cobra.Command{
 		Use:   "create",
 		Short: "Create an API Manager resource from a file",
@@ -111,19 +171,35 @@ For example:
 		// 	fmt.Println("delete called")
 		// },
 	}
+	updateCmd = &cobra.Command{
+		Use:   "update",
+		Short: "update an API Manager resource with flags",
+		Long: `update an API Manager resource by name with the flags of the create command,
+only the given flags are changed.
+
+	For example:
+
+	  # Secure a proxy with an API key
+	  apimanager update proxy -n 'Civil War' -s apikey -a Avengers
+		`,
+	}
 	editCmd = &cobra.Command{
 		Use:     "edit",
 		Aliases: []string{"edit"},
 		Short:   "edit an API Manager resource",
 		Long: `edit an API Manager resource by name. 
+
+The resource is opened in $KUBE_EDITOR, $EDITOR or vim as json, or as yaml
+with -o yaml. When the result is invalid the file is opened again with the
+error at the top, save an empty file to abort.
 	
 	For example:
 	
 	  # Edit an organization using the name
-	  apimanager describe org -n Avengers
+	  apimanager edit org -n Avengers
 	
-	  # Edit an application  using the name
-	  apimanager describe app -n Asgard
+	  # Edit an application as yaml
+	  apimanager edit app -n Asgard -o yaml
 		`,
 		// Run: func(cmd *cobra.Command, args []string) {
 		// 	fmt.Println("delete called")
@@ -133,11 +209,29 @@ For example:
 
 func init() {
 	rootCmd.AddCommand(loginCmd)
+	rootCmd.AddCommand(logoutCmd)
 	rootCmd.AddCommand(createCmd)
 	rootCmd.AddCommand(listCmd)
 	rootCmd.AddCommand(deleteCmd)
 	rootCmd.AddCommand(describeCmd)
 	rootCmd.AddCommand(editCmd)
+	rootCmd.AddCommand(updateCmd)
+
+	loginCmd.Flags().StringVar(&loginHost, "host", "", "API Manager hostname, defaults to $APIMANAGER_HOST")
+	loginCmd.Flags().StringVar(&loginPort, "port", "", "API Manager port, defaults to $APIMANAGER_PORT")
+	loginCmd.Flags().StringVar(&loginUser, "username", "", "API Manager username, defaults to $APIMANAGER_USERNAME")
+	loginCmd.Flags().BoolVar(&passwordStdin, "password-stdin", false, "read the password from stdin, defaults to $APIMANAGER_PASSWORD")
+	loginCmd.Flags().StringVar(&authMode, "auth-mode", "", "authentication mode: basic|session|auto, basic when not set")
+	loginCmd.Flags().StringVar(&caFile, "ca-file", "", "PEM file with the CA certificates that sign the API Manager certificate")
+	loginCmd.Flags().StringVar(&clientCert, "client-cert", "", "PEM client certificate for mutual TLS")
+	loginCmd.Flags().StringVar(&clientKey, "client-key", "", "PEM client key for mutual TLS")
+	loginCmd.Flags().StringVar(&tlsServerName, "tls-server-name", "", "server name used to verify the API Manager certificate")
+	loginCmd.Flags().BoolVar(&insecureSkipTLSVerify, "insecure-skip-tls-verify", false, "don't verify the API Manager certificate, insecure")
+	loginCmd.Flags().StringVar(&credentialHelper, "credential-helper", "", "program that stores the credentials instead of the encrypted credentials file")
+
+	addOutputFlag(listCmd)
+	addOutputFlag(describeCmd)
+	editCmd.PersistentFlags().StringVarP(&editFormat, "output", "o", "json", "format to edit in: json|yaml")
 
 	// Here you will define your flags and configuration settings.
 
@@ -162,33 +256,175 @@ func completer(d prompt.Document) []prompt.Suggest {
 
 func login(cmd *cobra.Command, args []string) {
 
-	fmt.Print("\nAPI Manager Hostname")
-	host := prompt.Input(": ", completer)
-	fmt.Print("API Manager Port")
-	port := prompt.Input(": ", completer)
-	fmt.Print("Username")
-	username := prompt.Input(": ", completer)
-	fmt.Print("Password")
-	password := prompt.Input(": ", completer)
+	interactive := term.IsTerminal(int(os.Stdin.Fd()))
+
+	host := loginValue(cmd, "host", loginHost, "APIMANAGER_HOST")
+	if host == "" && interactive {
+		fmt.Print("\nAPI Manager Hostname")
+		host = prompt.Input(": ", completer)
+	}
+	port := loginValue(cmd, "port", loginPort, "APIMANAGER_PORT")
+	if port == "" && interactive {
+		fmt.Print("API Manager Port")
+		port = prompt.Input(": ", completer)
+	}
+	username := loginValue(cmd, "username", loginUser, "APIMANAGER_USERNAME")
+	if username == "" && interactive {
+		fmt.Print("Username")
+		username = prompt.Input(": ", completer)
+	}
+
+	password := os.Getenv("APIMANAGER_PASSWORD")
+	if passwordStdin {
+		content, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			utils.PrettyPrintErr("Error reading the password from stdin: %v", err)
+			os.Exit(1)
+		}
+		password = strings.TrimRight(string(content), "\r\n")
+	} else if password == "" && interactive {
+		fmt.Print("Password: ")
+		content, err := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Println()
+		if err != nil {
+			utils.PrettyPrintErr("Error reading the password: %v", err)
+			os.Exit(1)
+		}
+		password = string(content)
+	}
 
-	conf := configAPI{}
+	if host == "" || port == "" || username == "" || password == "" {
+		utils.PrettyPrintErr("Hostname, port, username and password are required, use --host, --port, --username and --password-stdin or the APIMANAGER_HOST, APIMANAGER_PORT, APIMANAGER_USERNAME and APIMANAGER_PASSWORD environment variables")
+		os.Exit(1)
+	}
 
 	data := []byte(username + ":" + password)
 	basicAuth := base64.StdEncoding.EncodeToString(data)
 
+	file, err := loadConfigFile()
+	if err != nil {
+		fmt.Println("Error to read config yaml file", err)
+		return
+	}
+	name := contextName
+	if name == "" {
+		name = file.CurrentContext
+	}
+	if name == "" {
+		name = defaultContext
+	}
+	if file.Contexts == nil {
+		file.Contexts = map[string]configAPI{}
+	}
+
+	// settings that are not given are kept from the previous login
+	conf := file.Contexts[name]
 	conf.APIManagerHost = host
 	conf.APIManagerPort = port
-	conf.Authorization = basicAuth
+	conf.Authorization = ""
+	if cmd.Flags().Changed("credential-helper") {
+		conf.CredentialHelper = credentialHelper
+	}
+	if cmd.Flags().Changed("ca-file") {
+		conf.CAFile, err = absPath(caFile)
+	}
+	if err == nil && cmd.Flags().Changed("client-cert") {
+		conf.ClientCert, err = absPath(clientCert)
+	}
+	if err == nil && cmd.Flags().Changed("client-key") {
+		conf.ClientKey, err = absPath(clientKey)
+	}
+	if err != nil {
+		utils.PrettyPrintErr("%v", err)
+		os.Exit(1)
+	}
+	if cmd.Flags().Changed("auth-mode") {
+		conf.AuthMode = authMode
+	}
+	if cmd.Flags().Changed("tls-server-name") {
+		conf.TLSServerName = tlsServerName
+	}
+	if cmd.Flags().Changed("insecure-skip-tls-verify") {
+		conf.InsecureSkipTLSVerify = insecureSkipTLSVerify
+	}
+
+	// check the credentials before they are saved
+	verify := conf
+	verify.Authorization = basicAuth
+	// a session of the previous login is not valid for these credentials
+	eraseSession(name, conf)
+	cfg, err := newConfig(name, verify)
+	if err == nil {
+		_, err = apiRequest(cfg, "GET", "/currentuser", nil, "")
+	}
+	if err != nil {
+		utils.PrettyPrintErr("Login to %v:%v failed: %v", host, port, err)
+		os.Exit(1)
+	}
 
-	out, err := yaml.Marshal(conf)
+	err = getCredentialStore(conf).store(name, basicAuth)
 	if err != nil {
-		fmt.Println("Error to marshal config yaml", err)
+		fmt.Println("Error to store the credentials", err)
 		return
 	}
-	home := os.Getenv("HOME")
-	err = ioutil.WriteFile(home+"/.apimanager.yaml", out, 0644)
+	file.Contexts[name] = conf
+	file.CurrentContext = name
+
+	err = saveConfigFile(file)
 	if err != nil {
 		fmt.Println("Error to write config yaml file", err)
+		return
+	}
+	fmt.Printf("Login info stored in context %v\n", name)
+}
 
+// loginValue returns the value of the login flag, or of the environment variable
+// when the flag is not set
+func loginValue(cmd *cobra.Command, flag, value, env string) string {
+	if cmd.Flags().Changed(flag) {
+		return value
+	}
+	return os.Getenv(env)
+}
+
+// absPath makes a file given to login independent of the working directory
+func absPath(fileName string) (string, error) {
+	if fileName == "" {
+		return "", nil
+	}
+	return filepath.Abs(fileName)
+}
+
+func logout(cmd *cobra.Command, args []string) {
+	file, err := loadConfigFile()
+	if err != nil {
+		fmt.Println("Error to read config yaml file", err)
+		return
+	}
+	name := contextName
+	if name == "" {
+		name = file.CurrentContext
+	}
+	conf, ok := file.Contexts[name]
+	if !ok {
+		fmt.Println("Not logged in to API Manager")
+		return
+	}
+	err = getCredentialStore(conf).erase(name)
+	if err == nil {
+		err = eraseSession(name, conf)
+	}
+	if err != nil {
+		fmt.Println("Error to remove the credentials", err)
+		return
+	}
+	conf.Authorization = ""
+	file.Contexts[name] = conf
+
+	err = saveConfigFile(file)
+	if err != nil {
+		fmt.Println("Error to write config yaml file", err)
+		return
 	}
+	fmt.Printf("Credentials of context %v removed\n", name)
 }
@@ -0,0 +1,216 @@
+/*
+Copyright © 2020 Axway, Inc. <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Manifest kinds understood by apply
+const (
+	kindOrganization = "Organization"
+	kindUser         = "User"
+	kindApplication  = "Application"
+	kindBackendAPI   = "BackendAPI"
+	kindProxy        = "Proxy"
+	kindAPIKey       = "APIKey"
+	kindOAuthClient  = "OAuthClient"
+)
+
+// kindOrder is the order resources are applied in, a kind only refers to kinds before it
+var kindOrder = map[string]int{
+	kindOrganization: 0,
+	kindUser:         1,
+	kindApplication:  1,
+	kindBackendAPI:   1,
+	kindProxy:        2,
+	kindAPIKey:       2,
+	kindOAuthClient:  2,
+}
+
+// manifest is a single resource document. Spec holds the API Manager object as
+// returned by describe, references to other resources are given by name.
+type manifest struct {
+	Kind         string                    `json:"kind"`
+	Name         string                    `json:"name,omitempty"`
+	Organization string                    `json:"organization,omitempty"`
+	Application  string                    `json:"application,omitempty"`
+	BackendAPI   string                    `json:"backendAPI,omitempty"`
+	Applications []string                  `json:"applications,omitempty"`
+	Security     string                    `json:"security,omitempty"`
+	BackendAuth  *backendAuth              `json:"backendAuth,omitempty"`
+	CorsProfile  string                    `json:"corsProfile,omitempty"`
+	Cors         *corsSettings             `json:"cors,omitempty"`
+	Methods      map[string]methodOverride `json:"methods,omitempty"` // keyed by swagger operationId
+	Certs        []string                  `json:"certs,omitempty"`
+	Cert         string                    `json:"cert,omitempty"`
+	Swagger      string                    `json:"swagger,omitempty"`
+	Password     string                    `json:"password,omitempty"`
+	Spec         json.RawMessage           `json:"spec,omitempty"`
+
+	source string // file the manifest was read from
+}
+
+// path resolves a file referenced by the manifest relative to the manifest file
+func (m manifest) path(p string) string {
+	if p == "" || filepath.IsAbs(p) || m.source == "" {
+		return p
+	}
+	return filepath.Join(filepath.Dir(m.source), p)
+}
+
+func (m manifest) String() string {
+	if m.Name == "" {
+		return fmt.Sprintf("%s for %s", m.Kind, m.Application)
+	}
+	return m.Kind + "/" + m.Name
+}
+
+// loadManifests reads all manifests from the given files and directories
+func loadManifests(paths []string, recursive bool) ([]manifest, error) {
+	manifests := []manifest{}
+	for _, p := range paths {
+		files, err := manifestFiles(p, recursive)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range files {
+			content, err := ioutil.ReadFile(f)
+			if err != nil {
+				return nil, err
+			}
+			docs, err := decodeManifests(content)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", f, err)
+			}
+			for _, m := range docs {
+				m.source = f
+				manifests = append(manifests, m)
+			}
+		}
+	}
+	return manifests, nil
+}
+
+func manifestFiles(p string, recursive bool) ([]string, error) {
+	info, err := os.Stat(p)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{p}, nil
+	}
+	files := []string{}
+	err = filepath.Walk(p, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() {
+			if path != p && !recursive {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".yaml", ".yml", ".json":
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// decodeManifests splits YAML or JSON content into manifests. A document may
+// hold a single manifest or a list of manifests.
+func decodeManifests(content []byte) ([]manifest, error) {
+	manifests := []manifest{}
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	for {
+		var doc interface{}
+		err := decoder.Decode(&doc)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if doc == nil {
+			continue
+		}
+		items, ok := doc.([]interface{})
+		if !ok {
+			items = []interface{}{doc}
+		}
+		for _, item := range items {
+			m := manifest{}
+			if err := fromYAMLValue(item, &m); err != nil {
+				return nil, err
+			}
+			if _, ok := kindOrder[m.Kind]; !ok {
+				return nil, fmt.Errorf("unknown kind %q", m.Kind)
+			}
+			if m.Name == "" && m.Kind != kindAPIKey && m.Kind != kindOAuthClient {
+				return nil, fmt.Errorf("%s without a name", m.Kind)
+			}
+			manifests = append(manifests, m)
+		}
+	}
+	return manifests, nil
+}
+
+// sortManifests orders manifests so that referenced resources are applied first
+func sortManifests(manifests []manifest) {
+	sort.SliceStable(manifests, func(i, j int) bool {
+		return kindOrder[manifests[i].Kind] < kindOrder[manifests[j].Kind]
+	})
+}
+
+// fromYAMLValue converts a decoded YAML value into v using its json tags
+func fromYAMLValue(value interface{}, v interface{}) error {
+	content, err := json.Marshal(jsonValue(value))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(content, v)
+}
+
+// jsonValue replaces the map[interface{}]interface{} produced by yaml with maps json can encode
+func jsonValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		m := map[string]interface{}{}
+		for key, val := range v {
+			m[fmt.Sprint(key)] = jsonValue(val)
+		}
+		return m
+	case []interface{}:
+		for i, val := range v {
+			v[i] = jsonValue(val)
+		}
+	}
+	return value
+}
@@ -0,0 +1,84 @@
+/*
+Copyright © 2020 Axway, Inc. <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDecodeManifests(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+		wantErr bool
+	}{
+		{"empty", "", []string{}, false},
+		{"single", "kind: Organization\nname: Marvel\n", []string{"Organization/Marvel"}, false},
+		{"documents", "kind: Organization\nname: Marvel\n---\n---\nkind: Application\nname: Avengers\norganization: Marvel\n",
+			[]string{"Organization/Marvel", "Application/Avengers"}, false},
+		{"list", "- kind: User\n  name: tony\n- kind: APIKey\n  application: Avengers\n",
+			[]string{"User/tony", "APIKey for Avengers"}, false},
+		{"json", `{"kind": "BackendAPI", "name": "Captain America", "spec": {"summary": "Shield"}}`,
+			[]string{"BackendAPI/Captain America"}, false},
+		{"unknown kind", "kind: Team\nname: Avengers\n", nil, true},
+		{"no kind", "name: Avengers\n", nil, true},
+		{"no name", "kind: Proxy\n", nil, true},
+		{"invalid yaml", "kind: [Proxy\n", nil, true},
+	}
+	for _, test := range tests {
+		manifests, err := decodeManifests([]byte(test.content))
+		if (err != nil) != test.wantErr {
+			t.Errorf("%v: error = %v, want error %v", test.name, err, test.wantErr)
+			continue
+		}
+		if test.wantErr {
+			continue
+		}
+		got := []string{}
+		for _, m := range manifests {
+			got = append(got, m.String())
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%v: manifests = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestSortManifests(t *testing.T) {
+	manifests := []manifest{
+		{Kind: kindAPIKey, Application: "Avengers"},
+		{Kind: kindProxy, Name: "Civil War"},
+		{Kind: kindApplication, Name: "Avengers"},
+		{Kind: kindBackendAPI, Name: "Captain America"},
+		{Kind: kindOrganization, Name: "Marvel"},
+		{Kind: kindUser, Name: "tony"},
+		{Kind: kindOAuthClient, Name: "Jarvis", Application: "Avengers"},
+	}
+	sortManifests(manifests)
+	got := []string{}
+	for _, m := range manifests {
+		got = append(got, m.String())
+	}
+	// kinds of the same order keep the order they were given in
+	want := []string{"Organization/Marvel", "Application/Avengers", "BackendAPI/Captain America", "User/tony",
+		"APIKey for Avengers", "Proxy/Civil War", "OAuthClient/Jarvis"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("sorted manifests = %v, want %v", got, want)
+	}
+}
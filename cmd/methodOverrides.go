@@ -0,0 +1,230 @@
+/*
+Copyright © 2020 Axway, Inc. <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/skckadiyala/apimanager/apimgr"
+)
+
+// methodOverride replaces the proxy wide settings for one method of a proxy, it is
+// set in the methods field of a manifest keyed by the swagger operationId
+type methodOverride struct {
+	Security           string       `json:"security,omitempty"`
+	BackendAuth        *backendAuth `json:"backendAuth,omitempty"`
+	RequestPolicy      string       `json:"requestPolicy,omitempty"`
+	ResponsePolicy     string       `json:"responsePolicy,omitempty"`
+	RoutePolicy        string       `json:"routePolicy,omitempty"`
+	FaultHandlerPolicy string       `json:"faultHandlerPolicy,omitempty"`
+	// BackendMethod is the operationId of the backend API method the method is
+	// routed to, the backend path is the one of that method
+	BackendMethod string `json:"backendMethod,omitempty"`
+}
+
+// setMethodOverrides sets the inbound and outbound profiles of the proxy methods
+// given by operationId, the proxy must exist as the method ids are assigned by
+// API Manager. Files are resolved with path.
+func setMethodOverrides(cfg *apimgr.Configuration, proxy *apimgr.VirtualizedApi, methods map[string]methodOverride, path func(string) string) error {
+	operations, backendMethods, err := proxyMethods(cfg, *proxy)
+	if err != nil {
+		return err
+	}
+
+	// the maps are shared with the live proxy the changes are compared to
+	inboundProfiles := map[string]apimgr.InboundProfile{}
+	for name, inbound := range proxy.InboundProfiles {
+		inboundProfiles[name] = inbound
+	}
+	outboundProfiles := map[string]apimgr.OutboundProfile{}
+	for name, outbound := range proxy.OutboundProfiles {
+		outboundProfiles[name] = outbound
+	}
+
+	for operationID, override := range methods {
+		method, ok := proxyMethod(operations, backendMethods, operationID)
+		if !ok {
+			return fmt.Errorf("method %v not found in proxy %v", operationID, proxy.Name)
+		}
+
+		if override.Security != "" {
+			profile, err := methodSecurityProfile(override.Security)
+			if err != nil {
+				return fmt.Errorf("method %v: %v", operationID, err)
+			}
+			proxy.SecurityProfiles = replaceSecurityProfile(proxy.SecurityProfiles, profile)
+
+			inbound, ok := inboundProfiles[method.Id]
+			if !ok {
+				inbound = inboundProfiles["_default"]
+			}
+			inbound.SecurityProfile = profile.Name
+			inboundProfiles[method.Id] = inbound
+		}
+
+		outbound, ok := outboundProfiles[method.Id]
+		if !ok {
+			outbound = outboundProfiles["_default"]
+			outbound.ApiId = proxy.ApiId
+			outbound.ApiMethodId = method.ApiMethodId
+		}
+		if override.BackendMethod != "" {
+			backendMethod, ok := methodByName(backendMethods, override.BackendMethod)
+			if !ok {
+				return fmt.Errorf("method %v: backend method %v not found", operationID, override.BackendMethod)
+			}
+			outbound.ApiId = proxy.ApiId
+			outbound.ApiMethodId = backendMethod.Id
+		}
+		if override.BackendAuth != nil {
+			profile, err := override.BackendAuth.authenticationProfile(path)
+			if err != nil {
+				return fmt.Errorf("method %v: %v", operationID, err)
+			}
+			profile.Name = operationID
+			profile.IsDefault = false
+			proxy.AuthenticationProfiles = replaceAuthenticationProfile(proxy.AuthenticationProfiles, profile)
+			outbound.AuthenticationProfile = profile.Name
+		}
+		if override.RequestPolicy != "" {
+			outbound.RequestPolicy = policyKey(override.RequestPolicy)
+		}
+		if override.ResponsePolicy != "" {
+			outbound.ResponsePolicy = policyKey(override.ResponsePolicy)
+		}
+		if override.RoutePolicy != "" {
+			outbound.RouteType = "policy"
+			outbound.RoutePolicy = policyKey(override.RoutePolicy)
+		}
+		if override.FaultHandlerPolicy != "" {
+			outbound.FaultHandlerPolicy = policyKey(override.FaultHandlerPolicy)
+		}
+		if override.outbound() {
+			outboundProfiles[method.Id] = outbound
+		}
+	}
+	proxy.InboundProfiles = inboundProfiles
+	proxy.OutboundProfiles = outboundProfiles
+	return nil
+}
+
+// proxyMethods returns the methods of the proxy and of its backend API
+func proxyMethods(cfg *apimgr.Configuration, proxy apimgr.VirtualizedApi) ([]apimgr.ApiMethod, []apimgr.Method, error) {
+	operations := []apimgr.ApiMethod{}
+	content, err := apiRequest(cfg, "GET", "/proxies/"+proxy.Id+"/operations", nil, "")
+	if err == nil {
+		err = json.Unmarshal(content, &operations)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to get the methods of proxy %v: %v", proxy.Name, err)
+	}
+	backendMethods := []apimgr.Method{}
+	content, err = apiRequest(cfg, "GET", "/apirepo/"+proxy.ApiId+"/methods", nil, "")
+	if err == nil {
+		err = json.Unmarshal(content, &backendMethods)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to get the methods of the backend API of proxy %v: %v", proxy.Name, err)
+	}
+	return operations, backendMethods, nil
+}
+
+// outbound reports whether the override changes the outbound profile of the method
+func (o methodOverride) outbound() bool {
+	return o.BackendAuth != nil || o.BackendMethod != "" || o.RequestPolicy != "" || o.ResponsePolicy != "" ||
+		o.RoutePolicy != "" || o.FaultHandlerPolicy != ""
+}
+
+// proxyMethod returns the proxy method of the operation, the backend method named
+// after the operationId is looked up first as the proxy method may be renamed
+func proxyMethod(operations []apimgr.ApiMethod, backendMethods []apimgr.Method, operationID string) (apimgr.ApiMethod, bool) {
+	if backendMethod, ok := methodByName(backendMethods, operationID); ok {
+		for _, operation := range operations {
+			if operation.ApiMethodId == backendMethod.Id {
+				return operation, true
+			}
+		}
+	}
+	for _, operation := range operations {
+		if operation.Name == operationID {
+			return operation, true
+		}
+	}
+	return apimgr.ApiMethod{}, false
+}
+
+func methodByName(methods []apimgr.Method, name string) (apimgr.Method, bool) {
+	for _, method := range methods {
+		if method.Name == name {
+			return method, true
+		}
+	}
+	return apimgr.Method{}, false
+}
+
+// methodSecurityProfile returns the security profile for a method, it is named
+// after the security so methods with the same security share it
+func methodSecurityProfile(security string) (apimgr.SecurityProfile, error) {
+	profiles, err := getSecurityProfiles(security)
+	if err != nil {
+		return apimgr.SecurityProfile{}, err
+	}
+	profile := profiles[0]
+	for _, p := range profiles {
+		if p.IsDefault {
+			profile = p
+		}
+	}
+	profile.Name = security
+	profile.IsDefault = false
+	return profile, nil
+}
+
+// replaceSecurityProfile returns the profiles with the profile of the same name
+// replaced, or the profile appended
+func replaceSecurityProfile(profiles []apimgr.SecurityProfile, profile apimgr.SecurityProfile) []apimgr.SecurityProfile {
+	replaced := []apimgr.SecurityProfile{}
+	found := false
+	for _, p := range profiles {
+		if p.Name == profile.Name {
+			p, found = profile, true
+		}
+		replaced = append(replaced, p)
+	}
+	if !found {
+		replaced = append(replaced, profile)
+	}
+	return replaced
+}
+
+// replaceAuthenticationProfile returns the profiles with the profile of the same
+// name replaced, or the profile appended
+func replaceAuthenticationProfile(profiles []apimgr.AuthenticationProfile, profile apimgr.AuthenticationProfile) []apimgr.AuthenticationProfile {
+	replaced := []apimgr.AuthenticationProfile{}
+	found := false
+	for _, p := range profiles {
+		if p.Name == profile.Name {
+			p, found = profile, true
+		}
+		replaced = append(replaced, p)
+	}
+	if !found {
+		replaced = append(replaced, profile)
+	}
+	return replaced
+}
@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 	"io/ioutil"
+	"strings"
 
 	"github.com/skckadiyala/apimanager/apimgr"
 	"github.com/skckadiyala/kubecrt-vms/utils"
@@ -124,8 +125,6 @@ func listOAuthKeys(cmd *cobra.Command, args []string) {
 	cfg := getConfig()
 	client := &apimgr.APIClient{}
 	client = apimgr.NewAPIClient(cfg)
-	stdout := fmtDisplay()
-
 	appID := getApplicationByName(args)
 
 	oauths, _, err := client.ApplicationsApi.ApplicationsIdOauthGet(context.Background(), appID)
@@ -133,17 +132,11 @@ func listOAuthKeys(cmd *cobra.Command, args []string) {
 		utils.PrettyPrintErr("Error listing the oauth: %v", err)
 		return
 	}
-	if len(oauths) != 0 {
-		fmt.Fprintf(stdout, "OAUTH\tSECRET\n")
-		for _, oauth := range oauths {
-			fmt.Fprintf(stdout, "%v\t%v\n", oauth.Id, oauth.Secret)
-		}
-		fmt.Fprint(stdout)
-		stdout.Flush()
-	} else {
-		utils.PrettyPrintInfo("No OAuth Keys found in the application %v", appName)
-		return
+	t := newResourceTable("oauth", []string{"OAUTH", "SECRET"}, []string{"TYPE", "ENABLED", "REDIRECT URLS"})
+	for _, oauth := range oauths {
+		t.addRow(oauth.Id, oauth, oauth.Id, oauth.Secret, oauth.Type, oauth.Enabled, strings.Join(oauth.RedirectUrls, ","))
 	}
+	t.printList(fmt.Sprintf("No OAuth Keys found in the application %v", appName))
 }
 
 func deleteOAuthKey(cmd *cobra.Command, args []string) {
@@ -22,7 +22,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
-	"log"
 	"os"
 	"reflect"
 
@@ -206,21 +205,45 @@ func describeOrganization(cmd *cobra.Command, args []string) {
 	if err != nil {
 		return
 	}
-	prettyJSON, err := json.MarshalIndent(org, "", "    ")
+	t := organizationTable([]apimgr.Organization{org})
+	apis, err := organizationAPIs(getConfig(), org.Id)
 	if err != nil {
-		log.Fatal("Failed to marshal org to json", err)
+		// the organization is still described, without its APIs
+		utils.PrettyPrintErr("Unable to list the APIs of the Organization: %v", err)
+		t.printObject()
 		return
 	}
-	fmt.Printf("%s\n", prettyJSON)
+	t.objects[0] = organizationDescription{org, apis}
+	t.printObject()
+	if outputFormat == "table" || outputFormat == "wide" {
+		fmt.Println()
+		organizationAPITable(apis).printList("No APIs")
+	}
 	return
 }
 
+// organizationDescription is an organization with the APIs it can see, the fields
+// of the organization are kept as they are
+type organizationDescription struct {
+	apimgr.Organization
+	APIs []organizationAPI `json:"apis"`
+}
+
+func organizationAPITable(apis []organizationAPI) *resourceTable {
+	t := newResourceTable("proxy",
+		[]string{"API", "VERSION", "ACCESS", "ENABLED", "STATE"},
+		[]string{"PROXY ID"})
+	for _, api := range apis {
+		t.addRow(api.Name, api, api.Name, api.Version, api.Access, api.Enabled, api.State, api.ProxyID)
+	}
+	return t
+}
+
 func editOrganization(cmd *cobra.Command, args []string) {
 	cfg := getConfig()
 
 	eorg := apimgr.Organization{}
 
-	fname := getUniqueID(5)
 	client := &apimgr.APIClient{}
 	client = apimgr.NewAPIClient(cfg)
 
@@ -228,41 +251,30 @@ func editOrganization(cmd *cobra.Command, args []string) {
 	if err != nil {
 		return
 	}
-	prettyJSON, err := json.MarshalIndent(org, "", "    ")
-	if err != nil {
-		log.Fatal("Failed to marshal org to json", err)
+	err = editObject("Organization", org.Name, org, &eorg, func() error {
+		return checkImmutable("Organization", org, eorg, organizationImmutableFields...)
+	})
+	if err == errEditCancelled {
+		utils.PrettyPrintInfo("%v", err)
 		return
 	}
-
-	err = createTempFile(fname, prettyJSON)
 	if err != nil {
-		return
-	}
-
-	editedOrg, _ := ioutil.ReadFile("/tmp/" + fname)
-	err = json.Unmarshal(editedOrg, &eorg)
-	if err != nil {
-		fmt.Println("Invalid json object, no changes applied")
+		utils.PrettyPrintErr("%v", err)
 		return
 	}
 	orgVars := &apimgr.OrganizationsIdPutOpts{}
 	orgVars.Body = optional.NewInterface(eorg)
 
-	if eorg.Id != org.Id || eorg.Dn != org.Dn || eorg.CreatedOn != org.CreatedOn {
-		fmt.Println("Organization can't be updated with the changed values")
-		return
-	}
 	if reflect.DeepEqual(org, eorg) {
 		utils.PrettyPrintInfo("Organization %v has no changes to update", eorg.Name)
 		return
 	}
 	nOrg, _, err := client.OrganizationsApi.OrganizationsIdPut(context.Background(), eorg.Id, orgVars)
 	if err != nil {
-		utils.PrettyPrintErr("Error updating Organization")
+		utils.PrettyPrintErr("Error updating Organization: %v", err)
 		return
 	}
 	utils.PrettyPrintInfo("Organization %v updated with the valid changes", nOrg.Name)
-	deleteTempFile(fname)
 	return
 
 }
@@ -292,11 +304,30 @@ func getOrganizationByName(args []string) string {
 	return orgs[0].Id
 }
 
+// findOrganization returns the organization with the given name, or nil when it doesn't exist
+func findOrganization(cfg *apimgr.Configuration, name string) (*apimgr.Organization, error) {
+	client := apimgr.NewAPIClient(cfg)
+
+	getOrgVars := &apimgr.OrganizationsGetOpts{}
+
+	getOrgVars.Field = optional.NewInterface("name")
+	getOrgVars.Op = optional.NewInterface("eq")
+	getOrgVars.Value = optional.NewInterface(name)
+
+	orgs, _, err := client.OrganizationsApi.OrganizationsGet(context.Background(), getOrgVars)
+	if err != nil {
+		return nil, err
+	}
+	if len(orgs) == 0 {
+		return nil, nil
+	}
+	return &orgs[0], nil
+}
+
 func listOrganizations(cmd *cobra.Command, args []string) {
 	cfg := getConfig()
 	client := &apimgr.APIClient{}
 	client = apimgr.NewAPIClient(cfg)
-	stdout := fmtDisplay()
 	getOrgVars := &apimgr.OrganizationsGetOpts{}
 
 	orgs, _, err := client.OrganizationsApi.OrganizationsGet(context.Background(), getOrgVars)
@@ -304,16 +335,30 @@ func listOrganizations(cmd *cobra.Command, args []string) {
 		utils.PrettyPrintErr("Error listing the organizations: %v", err)
 		return
 	}
-	if len(orgs) != 0 {
-		fmt.Fprintf(stdout, "ID\tNAME\tDESCRIPTION\tCONTACT\n")
-		for _, org := range orgs {
-			fmt.Fprintf(stdout, "%v\t%v\t%v\t%v\n", org.Id, org.Name, org.Description, org.Email)
+	organizationTable(orgs).printList("No Organizations found ")
+}
+
+func organizationTable(orgs []apimgr.Organization) *resourceTable {
+	t := newResourceTable("organization",
+		[]string{"ID", "NAME", "DESCRIPTION", "CONTACT"},
+		[]string{"PHONE", "ENABLED", "DEVELOPMENT", "VIRTUAL HOST"})
+	for _, org := range orgs {
+		t.addRow(org.Name, org, org.Id, org.Name, org.Description, org.Email, org.Phone, org.Enabled, org.Development, org.VirtualHost)
+	}
+	return t
+}
+
+// organizationNames returns a lookup of organization names by ID
+func organizationNames(cfg *apimgr.Configuration) func(id string) string {
+	client := apimgr.NewAPIClient(cfg)
+	names := map[string]string{}
+	return func(id string) string {
+		if name, ok := names[id]; ok {
+			return name
 		}
-		fmt.Fprint(stdout)
-		stdout.Flush()
-	} else {
-		utils.PrettyPrintInfo("No Organizations found ")
-		return
+		org, _, _ := client.OrganizationsApi.OrganizationsIdGet(context.Background(), id)
+		names[id] = org.Name
+		return org.Name
 	}
 }
 
@@ -3,16 +3,17 @@ package cmd
 import (
 	"crypto/rand"
 	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"os"
-	"os/exec"
 	"text/tabwriter"
 
 	"git.ecd.axway.int/apigov/kubecrt-vms/utils"
 	"github.com/skckadiyala/apimanager/apimgr"
-	"github.com/spf13/viper"
 )
 
 var (
@@ -36,30 +37,160 @@ var (
 	image        string
 	enabled      bool
 	development  bool
+
+	loginHost     string
+	loginPort     string
+	loginUser     string
+	passwordStdin bool
+
+	caFile                string
+	clientCert            string
+	clientKey             string
+	tlsServerName         string
+	insecureSkipTLSVerify bool
 )
 
 type configAPI struct {
 	APIManagerHost string `yaml:"apiManagerHost"`
 	APIManagerPort string `yaml:"apiManagerPort"`
-	Authorization  string `yaml:"authorization"`
+	// Authorization is only read from configurations written by older versions,
+	// login keeps the credentials in the credential store
+	Authorization    string `yaml:"authorization,omitempty"`
+	CredentialHelper string `yaml:"credentialHelper,omitempty"`
+	// AuthMode is basic, session or auto, basic when empty
+	AuthMode string `yaml:"authMode,omitempty"`
+
+	// TLS settings, the API Manager certificate is verified against the
+	// system CA pool unless InsecureSkipTLSVerify is set
+	CAFile                string `yaml:"caFile,omitempty"`
+	ClientCert            string `yaml:"clientCert,omitempty"`
+	ClientKey             string `yaml:"clientKey,omitempty"`
+	TLSServerName         string `yaml:"tlsServerName,omitempty"`
+	InsecureSkipTLSVerify bool   `yaml:"insecureSkipTLSVerify,omitempty"`
 }
 
 func getConfig() *apimgr.Configuration {
-	if viper.GetString("apimanagerhost") == "" || viper.GetString("apimanagerport") == "" {
+	name, api, err := currentConfig()
+	if err != nil {
+		utils.PrettyPrintErr("%v", err)
+		os.Exit(0)
+	}
+	if api.APIManagerHost == "" || api.APIManagerPort == "" {
 		utils.PrettyPrintErr("Please login to API Manager, use 'login' command")
 		os.Exit(0)
 	}
+	if api.Authorization == "" && name != "" {
+		api.Authorization, err = getCredentialStore(api).get(name)
+		if err != nil {
+			utils.PrettyPrintErr("%v", err)
+			os.Exit(0)
+		}
+	}
+	cfg, err := newConfig(name, api)
+	if err != nil {
+		utils.PrettyPrintErr("%v", err)
+		os.Exit(0)
+	}
+	return cfg
+}
 
-	transCfg := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true}, // ignore expired SSL certificates
+// newConfig returns the client configuration of an API Manager instance, name is
+// the context the session is cached for
+func newConfig(name string, api configAPI) (*apimgr.Configuration, error) {
+	tlsCfg, err := newTLSConfig(api)
+	if err != nil {
+		return nil, err
 	}
+	transCfg := &http.Transport{TLSClientConfig: tlsCfg}
 
 	cfg := apimgr.NewConfiguration()
-	cfg.Host = viper.GetString("apimanagerhost") + ":" + viper.GetString("apimanagerport")
+	cfg.Host = api.APIManagerHost + ":" + api.APIManagerPort
 	cfg.Scheme = "https"
-	cfg.AddDefaultHeader("Authorization", "Basic "+viper.GetString("authorization"))
-	cfg.HTTPClient = &http.Client{Transport: transCfg}
-	return cfg
+	switch api.AuthMode {
+	case "", authBasic:
+		cfg.AddDefaultHeader("Authorization", "Basic "+api.Authorization)
+		cfg.HTTPClient = &http.Client{Transport: transCfg}
+	case authSession, authAuto:
+		base, err := url.Parse(cfg.BasePath)
+		if err != nil {
+			return nil, err
+		}
+		loginURL := url.URL{Scheme: cfg.Scheme, Host: cfg.Host, Path: base.Path + "/login"}
+		cfg.HTTPClient = &http.Client{Transport: newSessionTransport(transCfg, loginURL.String(), name, api)}
+	default:
+		return nil, fmt.Errorf("unknown auth mode %q, allowed modes: basic|session|auto", api.AuthMode)
+	}
+	return cfg, nil
+}
+
+// newTLSConfig returns the TLS settings of the context
+func newTLSConfig(api configAPI) (*tls.Config, error) {
+	tlsCfg := &tls.Config{
+		ServerName:         api.TLSServerName,
+		InsecureSkipVerify: api.InsecureSkipTLSVerify,
+	}
+	if api.CAFile != "" {
+		caCerts, err := ioutil.ReadFile(api.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read the CA file: %v", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(caCerts) {
+			return nil, fmt.Errorf("no PEM certificates found in the CA file %v", api.CAFile)
+		}
+		tlsCfg.RootCAs = pool
+	}
+	if api.ClientCert != "" || api.ClientKey != "" {
+		if api.ClientCert == "" || api.ClientKey == "" {
+			return nil, fmt.Errorf("both the client certificate and the client key are required")
+		}
+		cert, err := tls.LoadX509KeyPair(api.ClientCert, api.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load the client certificate: %v", err)
+		}
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+	return tlsCfg, nil
+}
+
+// apiRequest calls an API Manager endpoint that the generated client doesn't cover,
+// path is relative to the API Manager base path
+func apiRequest(cfg *apimgr.Configuration, method, path string, body io.Reader, contentType string) ([]byte, error) {
+	base, err := url.Parse(cfg.BasePath)
+	if err != nil {
+		return nil, err
+	}
+	rel, err := url.Parse(path)
+	if err != nil {
+		return nil, err
+	}
+	reqURL := url.URL{Scheme: cfg.Scheme, Host: cfg.Host, Path: base.Path + rel.Path, RawQuery: rel.RawQuery}
+	req, err := http.NewRequest(method, reqURL.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range cfg.DefaultHeader {
+		req.Header.Set(key, value)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := cfg.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	content, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		return content, fmt.Errorf("%s %s: %s", method, path, resp.Status)
+	}
+	return content, nil
 }
 
 func fmtDisplay() *tabwriter.Writer {
@@ -77,30 +208,3 @@ func getUniqueID(nbofBytes int) string {
 	uuid := fmt.Sprintf("%x", b[0:nbofBytes])
 	return uuid
 }
-
-func createTempFile(fileName string, content []byte) error {
-	err := ioutil.WriteFile("/tmp/"+fileName, content, 0644)
-	if err != nil {
-		fmt.Println("Failed to write temp file", err)
-		return err
-	}
-	cm := exec.Command("vim", "/tmp/"+fileName)
-	cm.Stdin = os.Stdin
-	cm.Stdout = os.Stdout
-	cm.Stderr = os.Stderr
-
-	err = cm.Run()
-	if err != nil {
-		fmt.Println("Failed to open editor", err)
-		return err
-	}
-	return nil
-}
-func deleteTempFile(fileName string) {
-	cmd := exec.Command("rm", "/tmp/"+fileName)
-	err := cmd.Run()
-	if err != nil {
-		fmt.Print(err)
-		return
-	}
-}
@@ -0,0 +1,350 @@
+/*
+Copyright © 2020 Axway, Inc. <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+
+	"github.com/antihax/optional"
+	jsonpatch "github.com/evanphx/json-patch"
+	"github.com/skckadiyala/apimanager/apimgr"
+	"github.com/skckadiyala/kubecrt-vms/utils"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+var (
+	patchContent string
+	patchFile    string
+	patchType    string
+)
+
+// patchCmd represents the patch command
+var (
+	patchCmd = &cobra.Command{
+		Use:   "patch",
+		Short: "Update fields of an API Manager resource",
+		Long: `Update fields of an API Manager resource with a JSON merge patch (RFC 7386),
+the default, or a JSON patch (RFC 6902) with --type json. The patch is given
+inline with -p or read from a file with --patch-file, json and yaml are accepted.
+Ids and the other fields that can't be edited can't be patched either.
+
+For example:
+
+  # Enable an organization
+  apimanager patch org -n Marvel -p '{"enabled": true}'
+
+  # Change the role of a user
+  apimanager patch user -n 'Iron Man' -p '{"role": "oadmin"}'
+
+  # Change the email of an application with a JSON patch
+  apimanager patch app -n Avengers --type json -p '[{"op": "replace", "path": "/email", "value": "avengers@marvel.com"}]'
+
+  # Change the path of a proxy from a file
+  apimanager patch proxy -n 'Civil War' --patch-file path.yaml
+`,
+	}
+	orgPatchCmd = &cobra.Command{
+		Use:     "org",
+		Aliases: []string{"organization"},
+		Short:   "Patch an organization",
+		Run:     patchOrganization,
+	}
+	userPatchCmd = &cobra.Command{
+		Use:   "user",
+		Short: "Patch a user",
+		Run:   patchUser,
+	}
+	appPatchCmd = &cobra.Command{
+		Use:   "app",
+		Short: "Patch an application",
+		Run:   patchApplication,
+	}
+	proxyPatchCmd = &cobra.Command{
+		Use:   "proxy",
+		Short: "Patch a proxy",
+		Run:   patchProxy,
+	}
+	apiPatchCmd = &cobra.Command{
+		Use:   "api",
+		Short: "Patch a backend API",
+		Run:   patchAPI,
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(patchCmd)
+	patchCmd.AddCommand(orgPatchCmd)
+	patchCmd.AddCommand(userPatchCmd)
+	patchCmd.AddCommand(appPatchCmd)
+	patchCmd.AddCommand(proxyPatchCmd)
+	patchCmd.AddCommand(apiPatchCmd)
+
+	patchCmd.PersistentFlags().StringVarP(&patchContent, "patch", "p", "", "the patch to apply")
+	patchCmd.PersistentFlags().StringVar(&patchFile, "patch-file", "", "file with the patch to apply")
+	patchCmd.PersistentFlags().StringVar(&patchType, "type", "merge", "patch type: merge|json")
+
+	orgPatchCmd.Flags().StringVarP(&orgName, "name", "n", "", "The name to store Organization name")
+	orgPatchCmd.MarkFlagRequired("name")
+	userPatchCmd.Flags().StringVarP(&userName, "name", "n", "", "The name of the username")
+	userPatchCmd.MarkFlagRequired("name")
+	appPatchCmd.Flags().StringVarP(&appName, "name", "n", "", "The name to store application name")
+	appPatchCmd.MarkFlagRequired("name")
+	proxyPatchCmd.Flags().StringVarP(&name, "name", "n", "", "proxy name")
+	proxyPatchCmd.MarkFlagRequired("name")
+	apiPatchCmd.Flags().StringVarP(&apiName, "name", "n", "", "The name to store API name")
+	apiPatchCmd.MarkFlagRequired("name")
+}
+
+func patchOrganization(cmd *cobra.Command, args []string) {
+	cfg := getConfig()
+	client := apimgr.NewAPIClient(cfg)
+
+	orgID, err := resolveOrganizationID(cfg, orgName)
+	if err != nil {
+		utils.PrettyPrintErr("%v", err)
+		os.Exit(1)
+	}
+	org, _, err := client.OrganizationsApi.OrganizationsIdGet(context.Background(), orgID)
+	if err != nil {
+		utils.PrettyPrintErr("Unable to get the Organization: %v", err)
+		os.Exit(1)
+	}
+	patched := apimgr.Organization{}
+	if err = applyPatch(org, &patched); err == nil {
+		err = checkImmutable("Organization", org, patched, organizationImmutableFields...)
+	}
+	if err != nil {
+		utils.PrettyPrintErr("Error patching Organization: %v", err)
+		os.Exit(1)
+	}
+	if reflect.DeepEqual(org, patched) {
+		utils.PrettyPrintInfo("Organization %v has no changes to update", org.Name)
+		return
+	}
+	orgVars := &apimgr.OrganizationsIdPutOpts{}
+	orgVars.Body = optional.NewInterface(patched)
+
+	nOrg, _, err := client.OrganizationsApi.OrganizationsIdPut(context.Background(), patched.Id, orgVars)
+	if err != nil {
+		utils.PrettyPrintErr("Error updating Organization: %v", err)
+		os.Exit(1)
+	}
+	utils.PrettyPrintInfo("Organization %v patched", nOrg.Name)
+}
+
+func patchUser(cmd *cobra.Command, args []string) {
+	cfg := getConfig()
+	client := apimgr.NewAPIClient(cfg)
+
+	found, err := findUser(cfg, userName)
+	if err == nil && found == nil {
+		err = fmt.Errorf("user %v not found", userName)
+	}
+	if err != nil {
+		utils.PrettyPrintErr("%v", err)
+		os.Exit(1)
+	}
+	user, _, err := client.UsersApi.UsersIdGet(context.Background(), found.Id)
+	if err != nil {
+		utils.PrettyPrintErr("Unable to get the user: %v", err)
+		os.Exit(1)
+	}
+	patched := apimgr.User{}
+	if err = applyPatch(user, &patched); err == nil {
+		err = checkImmutable("User", user, patched, userImmutableFields...)
+	}
+	if err != nil {
+		utils.PrettyPrintErr("Error patching User: %v", err)
+		os.Exit(1)
+	}
+	if reflect.DeepEqual(user, patched) {
+		utils.PrettyPrintInfo("User %v has no changes to update", user.Name)
+		return
+	}
+	userVars := &apimgr.UsersIdPutOpts{}
+	userVars.Body = optional.NewInterface(patched)
+
+	updatedUser, _, err := client.UsersApi.UsersIdPut(context.Background(), patched.Id, userVars)
+	if err != nil {
+		utils.PrettyPrintErr("Error updating User: %v", err)
+		os.Exit(1)
+	}
+	utils.PrettyPrintInfo("User %v patched", updatedUser.Name)
+}
+
+func patchApplication(cmd *cobra.Command, args []string) {
+	cfg := getConfig()
+	client := apimgr.NewAPIClient(cfg)
+
+	appID, err := resolveApplicationID(cfg, appName)
+	if err != nil {
+		utils.PrettyPrintErr("%v", err)
+		os.Exit(1)
+	}
+	app, _, err := client.ApplicationsApi.ApplicationsIdGet(context.Background(), appID)
+	if err != nil {
+		utils.PrettyPrintErr("Unable to get the application: %v", err)
+		os.Exit(1)
+	}
+	patched := apimgr.Application{}
+	if err = applyPatch(app, &patched); err == nil {
+		err = checkImmutable("Application", app, patched, applicationImmutableFields...)
+	}
+	if err != nil {
+		utils.PrettyPrintErr("Error patching Application: %v", err)
+		os.Exit(1)
+	}
+	if reflect.DeepEqual(app, patched) {
+		utils.PrettyPrintInfo("Application %v has no changes to update", app.Name)
+		return
+	}
+	appVars := &apimgr.ApplicationsIdPutOpts{}
+	appVars.Body = optional.NewInterface(patched)
+
+	updatedApp, _, err := client.ApplicationsApi.ApplicationsIdPut(context.Background(), patched.Id, appVars)
+	if err != nil {
+		utils.PrettyPrintErr("Error updating Application: %v", err)
+		os.Exit(1)
+	}
+	utils.PrettyPrintInfo("Application %v patched", updatedApp.Name)
+}
+
+func patchProxy(cmd *cobra.Command, args []string) {
+	cfg := getConfig()
+	client := apimgr.NewAPIClient(cfg)
+
+	proxy, err := getProxyByName(args, cfg)
+	if err != nil {
+		utils.PrettyPrintErr("unable to find the proxy : %v", err)
+		os.Exit(1)
+	}
+	proxy, _, err = client.APIProxyRegistrationApi.ProxiesIdGet(context.Background(), proxy.Id)
+	if err != nil {
+		utils.PrettyPrintErr("Unable to get the Proxy: %v", err)
+		os.Exit(1)
+	}
+	patched := apimgr.VirtualizedApi{}
+	if err = applyPatch(proxy, &patched); err == nil {
+		err = checkImmutable("Proxy", proxy, patched, proxyImmutableFields...)
+	}
+	if err != nil {
+		utils.PrettyPrintErr("Error patching Proxy: %v", err)
+		os.Exit(1)
+	}
+	if reflect.DeepEqual(proxy, patched) {
+		utils.PrettyPrintInfo("Proxy %v has no changes to update", proxy.Name)
+		return
+	}
+	updatedProxy, err := putProxy(cfg, proxy, patched)
+	if err != nil {
+		utils.PrettyPrintErr("Error updating Proxy: %v", err)
+		os.Exit(1)
+	}
+	utils.PrettyPrintInfo("Proxy %v patched", updatedProxy.Name)
+}
+
+func patchAPI(cmd *cobra.Command, args []string) {
+	cfg := getConfig()
+	client := apimgr.NewAPIClient(cfg)
+	apiID, err := resolveBackendAPIID(cfg, apiName)
+	if err != nil {
+		utils.PrettyPrintErr("%v", err)
+		os.Exit(1)
+	}
+	api, _, err := client.APIRepositoryApi.ApirepoIdGet(context.Background(), apiID)
+	if err != nil {
+		utils.PrettyPrintErr("Unable to get the backend API: %v", err)
+		os.Exit(1)
+	}
+	patched := apimgr.Api{}
+	if err = applyPatch(api, &patched); err == nil {
+		err = checkImmutable("Backend API", api, patched, backendAPIImmutableFields...)
+	}
+	if err != nil {
+		utils.PrettyPrintErr("Error patching Backend API: %v", err)
+		os.Exit(1)
+	}
+	if reflect.DeepEqual(api, patched) {
+		utils.PrettyPrintInfo("Backend API %v has no changes to update", api.Name)
+		return
+	}
+	updatedAPI, _, err := client.APIRepositoryApi.ApirepoIdPut(context.Background(), patched.Id, patched)
+	if err != nil {
+		utils.PrettyPrintErr("Error updating Backend API: %v", err)
+		os.Exit(1)
+	}
+	utils.PrettyPrintInfo("Backend API %v patched", updatedAPI.Name)
+}
+
+// applyPatch applies the patch given by -p or --patch-file to live and decodes the result into patched
+func applyPatch(live, patched interface{}) error {
+	patch, err := readPatch()
+	if err != nil {
+		return err
+	}
+	doc, err := json.Marshal(live)
+	if err != nil {
+		return err
+	}
+	switch patchType {
+	case "merge":
+		doc, err = jsonpatch.MergePatch(doc, patch)
+	case "json":
+		var ops jsonpatch.Patch
+		if ops, err = jsonpatch.DecodePatch(patch); err == nil {
+			doc, err = ops.Apply(doc)
+		}
+	default:
+		return fmt.Errorf("unknown patch type %q, allowed types: merge|json", patchType)
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(doc, patched)
+}
+
+// readPatch returns the patch as json, a yaml patch is converted
+func readPatch() ([]byte, error) {
+	patch := []byte(patchContent)
+	if patchFile != "" {
+		if patchContent != "" {
+			return nil, fmt.Errorf("either --patch or --patch-file is allowed")
+		}
+		content, err := ioutil.ReadFile(patchFile)
+		if err != nil {
+			return nil, err
+		}
+		patch = content
+	}
+	if len(patch) == 0 {
+		return nil, fmt.Errorf("a patch is required, use --patch or --patch-file")
+	}
+	if json.Valid(patch) {
+		return patch, nil
+	}
+	var value interface{}
+	if err := yaml.Unmarshal(patch, &value); err != nil {
+		return nil, fmt.Errorf("the patch is neither json nor yaml: %v", err)
+	}
+	return json.Marshal(jsonValue(value))
+}
@@ -0,0 +1,235 @@
+/*
+Copyright © 2020 Axway, Inc. <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/skckadiyala/kubecrt-vms/utils"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+	"k8s.io/client-go/util/jsonpath"
+)
+
+var outputFormat string
+
+const outputFormats = "table|wide|json|yaml|name|csv|jsonpath=<expr>|jsonpath-file=<file>|go-template=<template>|go-template-file=<file>"
+
+// addOutputFlag adds the -o/--output flag to the command and its sub commands
+func addOutputFlag(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", "", "output format: "+outputFormats)
+}
+
+// resourceTable holds the resources a list or describe command prints. Each
+// row has a value for every column followed by a value for every wide column.
+type resourceTable struct {
+	kind    string
+	columns []string
+	wide    []string
+	rows    [][]string
+	names   []string
+	objects []interface{}
+}
+
+func newResourceTable(kind string, columns, wide []string) *resourceTable {
+	return &resourceTable{kind: kind, columns: columns, wide: wide}
+}
+
+// addRow adds a resource with its name, the API object and the column values
+func (t *resourceTable) addRow(name string, obj interface{}, values ...interface{}) {
+	row := make([]string, len(values))
+	for i, value := range values {
+		row[i] = fmt.Sprint(value)
+	}
+	t.rows = append(t.rows, row)
+	t.names = append(t.names, name)
+	t.objects = append(t.objects, obj)
+}
+
+// printList prints the resources in the selected output format, a table by default.
+// empty is printed for tables without rows.
+func (t *resourceTable) printList(empty string) {
+	if len(t.rows) == 0 && (outputFormat == "" || outputFormat == "table" || outputFormat == "wide") {
+		utils.PrettyPrintInfo(empty)
+		return
+	}
+	if err := t.print(outputFormat, false); err != nil {
+		utils.PrettyPrintErr("%v", err)
+	}
+}
+
+// printObject prints a single resource in the selected output format, indented json by default
+func (t *resourceTable) printObject() {
+	format := outputFormat
+	if format == "" {
+		format = "json"
+	}
+	if err := t.print(format, true); err != nil {
+		utils.PrettyPrintErr("%v", err)
+	}
+}
+
+func (t *resourceTable) print(format string, single bool) error {
+	format, arg := splitOutputFormat(format)
+	switch format {
+	case "", "table":
+		return t.printTable(false)
+	case "wide":
+		return t.printTable(true)
+	case "name":
+		for _, name := range t.names {
+			fmt.Printf("%s/%s\n", t.kind, name)
+		}
+		return nil
+	case "csv":
+		return t.printCSV()
+	case "json":
+		content, err := json.MarshalIndent(t.document(single), "", "    ")
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s\n", content)
+		return nil
+	case "yaml":
+		content, err := toYAML(t.document(single))
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s", content)
+		return nil
+	case "jsonpath", "jsonpath-file":
+		if format == "jsonpath-file" {
+			content, err := ioutil.ReadFile(arg)
+			if err != nil {
+				return err
+			}
+			arg = string(content)
+		}
+		return t.printJSONPath(arg, single)
+	case "go-template", "go-template-file":
+		if format == "go-template-file" {
+			content, err := ioutil.ReadFile(arg)
+			if err != nil {
+				return err
+			}
+			arg = string(content)
+		}
+		return t.printTemplate(arg, single)
+	}
+	return fmt.Errorf("unknown output format %q, allowed formats: %s", format, outputFormats)
+}
+
+func splitOutputFormat(format string) (string, string) {
+	parts := strings.SplitN(format, "=", 2)
+	if len(parts) == 1 {
+		return parts[0], ""
+	}
+	return parts[0], parts[1]
+}
+
+func (t *resourceTable) printTable(wide bool) error {
+	columns := t.columns
+	if wide {
+		columns = append(append([]string{}, t.columns...), t.wide...)
+	}
+	stdout := fmtDisplay()
+	fmt.Fprintf(stdout, "%s\n", strings.Join(columns, "\t"))
+	for _, row := range t.rows {
+		fmt.Fprintf(stdout, "%s\n", strings.Join(row[:len(columns)], "\t"))
+	}
+	return stdout.Flush()
+}
+
+func (t *resourceTable) printCSV() error {
+	out := csv.NewWriter(os.Stdout)
+	out.Write(append(append([]string{}, t.columns...), t.wide...))
+	for _, row := range t.rows {
+		out.Write(row)
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// document returns the object of a single resource or the items of a list
+func (t *resourceTable) document(single bool) interface{} {
+	if single && len(t.objects) == 1 {
+		return t.objects[0]
+	}
+	return map[string]interface{}{"items": t.objects}
+}
+
+// data returns the document as generic JSON values so templates use the json field names
+func (t *resourceTable) data(single bool) (interface{}, error) {
+	content, err := json.Marshal(t.document(single))
+	if err != nil {
+		return nil, err
+	}
+	var data interface{}
+	err = json.Unmarshal(content, &data)
+	return data, err
+}
+
+func (t *resourceTable) printJSONPath(expr string, single bool) error {
+	if !strings.HasPrefix(expr, "{") {
+		expr = "{" + expr + "}"
+	}
+	jp := jsonpath.New("output")
+	jp.AllowMissingKeys(true)
+	if err := jp.Parse(expr); err != nil {
+		return fmt.Errorf("invalid jsonpath %q: %v", expr, err)
+	}
+	data, err := t.data(single)
+	if err != nil {
+		return err
+	}
+	if err = jp.Execute(os.Stdout, data); err != nil {
+		return err
+	}
+	fmt.Println()
+	return nil
+}
+
+func (t *resourceTable) printTemplate(text string, single bool) error {
+	tmpl, err := template.New("output").Parse(text)
+	if err != nil {
+		return fmt.Errorf("invalid template: %v", err)
+	}
+	data, err := t.data(single)
+	if err != nil {
+		return err
+	}
+	return tmpl.Execute(os.Stdout, data)
+}
+
+// toYAML encodes obj as YAML using its json field names and order
+func toYAML(obj interface{}) ([]byte, error) {
+	content, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	doc := yaml.MapSlice{}
+	if err = yaml.Unmarshal(content, &doc); err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(doc)
+}
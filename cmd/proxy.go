@@ -18,11 +18,14 @@ package cmd
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
+	"net/http"
 	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/antihax/optional"
 	"github.com/skckadiyala/apimanager/apimgr"
@@ -41,9 +44,18 @@ For example:
 
 # Create proxy using the data 
 apimanager create proxy -f proxy.json
-apimanager create proxy -n <name> -a 'Backend API' -o <orgName> -s passthrough`,
+apimanager create proxy -n <name> -a 'Backend API' -o <orgName> -s passthrough
+
+# Accept an API key or an OAuth token
+apimanager create proxy -n <name> -b 'Backend API' -o <orgName> -s apikey,oauth -a <appName>
+
+# Validate JWTs with a policy, jwt is an authpolicy device named JWT
+apimanager create proxy -n <name> -b 'Backend API' -o <orgName> -s jwt --auth-policy 'Verify JWT' -a <appName>
+
+# Publish with a complete API Catalog entry
+apimanager create proxy -n <name> -b 'Backend API' -o <orgName> --display-name 'Bank API' --tag domain=banking --summary 'Accounts and payments' --description-file bank.md -i bank.png`,
 		PreRun: func(cmd *cobra.Command, args []string) {
-			if security != "passthrough" {
+			if security != "passthrough" && !cmd.Flags().Changed("security-profile-file") {
 				cmd.MarkFlagRequired("appName")
 			}
 		},
@@ -57,7 +69,10 @@ apimanager create proxy -n <name> -a 'Backend API' -o <orgName> -s passthrough`,
 For example:
 
 # list all proxy 
-apimanager list proxies `,
+apimanager list proxies 
+
+# list the versions of each proxy, newest first
+apimanager list proxies --versions`,
 		Run: listProxies,
 	}
 	proxyDelete = &cobra.Command{
@@ -68,7 +83,16 @@ apimanager list proxies `,
 For example:
 
 # Delete a proxy 
-apimanager delete proxy -n <ProxyName> `,
+apimanager delete proxy -n <ProxyName> 
+
+# Delete the unpublished proxies of a test organization without confirmation
+apimanager delete proxy --org <orgName> --state unpublished --yes
+
+A deprecated proxy that applications still have access to is only deleted
+with --force. Proxies are selected with --org, --tag, --state, --name-regex,
+--all or --from-file instead of -n, a proxy has to match all of them. The
+selected proxies are listed and deleted after confirmation, --parallel at a
+time.`,
 		Run: deleteProxy,
 	}
 
@@ -83,6 +107,41 @@ For example:
 apimanager describe proxy -n <ProxyName> `,
 		Run: describeProxy,
 	}
+
+	proxyUpdate = &cobra.Command{
+		Use:   "proxy",
+		Short: "Update a proxy",
+		Long: `Update a proxy with the flags of create proxy, only the given flags are
+changed. The organization and backend API of a proxy can't be changed. A
+published proxy that can't be updated is unpublished, updated and published
+again. Application access is kept, -a grants access to one more application.
+
+For example:
+
+# Secure a proxy with an API key 
+apimanager update proxy -n <ProxyName> -s apikey -a <appName>
+
+# Move a proxy to a new path and version 
+apimanager update proxy -n <ProxyName> -r /bank/v2 -v 2.0
+
+# Unpublish a proxy 
+apimanager update proxy -n <ProxyName> -p unpublished`,
+		Run: updateProxy,
+	}
+
+	proxyEdit = &cobra.Command{
+		Use:   "proxy",
+		Short: "Edit a proxy",
+		Long: `Edit a proxy by name. The id, organization, backend API, state and
+creation fields can't be changed. A published proxy that can't be updated is
+unpublished, updated and published again.
+
+For example:
+
+# Edit a proxy 
+apimanager edit proxy -n <ProxyName> `,
+		Run: editProxy,
+	}
 )
 
 func init() {
@@ -90,47 +149,65 @@ func init() {
 	listCmd.AddCommand(proxyList)
 	deleteCmd.AddCommand(proxyDelete)
 	describeCmd.AddCommand(proxyDescribe)
+	editCmd.AddCommand(proxyEdit)
+	updateCmd.AddCommand(proxyUpdate)
+
+	proxyList.Flags().BoolVar(&listVersions, "versions", false, "group the versions of each proxy, newest first")
 
 	proxyDelete.Flags().StringVarP(&name, "name", "n", "", "proxy name")
-	proxyDelete.MarkFlagRequired("name")
+	proxyDelete.Flags().BoolVar(&forceDelete, "force", false, "delete a deprecated proxy that applications still have access to")
+	addSelectorFlags(proxyDelete, true)
 
 	proxyDescribe.Flags().StringVarP(&name, "name", "n", "", "proxy name")
 	proxyDescribe.MarkFlagRequired("name")
 
-	proxyCmd.Flags().StringVarP(&file, "file", "f", "", "The filename of the swagger api to be stored")
+	proxyEdit.Flags().StringVarP(&name, "name", "n", "", "proxy name")
+	proxyEdit.MarkFlagRequired("name")
 
-	proxyCmd.Flags().StringVarP(&name, "name", "n", "", "proxy name")
+	proxyCmd.Flags().StringVarP(&file, "file", "f", "", "The filename of the swagger api to be stored")
+	addProxyFlags(proxyCmd)
 	proxyCmd.MarkFlagRequired("name")
-	proxyCmd.Flags().StringVarP(&orgName, "orgName", "o", "", "organization name")
 	proxyCmd.MarkFlagRequired("orgName")
-	proxyCmd.Flags().StringVarP(&apiName, "apiName", "b", "", "backend API name")
 	proxyCmd.MarkFlagRequired("apiName")
 
-	proxyCmd.Flags().StringVarP(&appName, "appName", "a", "", "application name")
-	proxyCmd.Flags().StringVarP(&security, "security", "s", "passthrough", "provide the security to use for proxy: \napikey \nhttpbasic \noauth \npassthrough")
-	proxyCmd.Flags().StringVarP(&resourcePath, "resourcePath", "r", "", "provide the resource path for the proxy")
-	proxyCmd.Flags().StringVarP(&certPath, "certPath", "c", "", "provide the location of the backend api cert")
-
-	proxyCmd.Flags().StringVarP(&proxyVersion, "proxyVersion", "v", "1.0", "provide the proxy version")
-	proxyCmd.Flags().StringVarP(&proxyState, "proxyState", "p", "published", "provide the proxy state")
+	addProxyFlags(proxyUpdate)
+	proxyUpdate.MarkFlagRequired("name")
+}
 
+// addProxyFlags adds the flags create proxy and update proxy share
+func addProxyFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&name, "name", "n", "", "proxy name")
+	cmd.Flags().StringVarP(&orgName, "orgName", "o", "", "organization name")
+	cmd.Flags().StringVarP(&apiName, "apiName", "b", "", "backend API name")
+
+	cmd.Flags().StringVarP(&appName, "appName", "a", "", "application name")
+	cmd.Flags().StringVarP(&security, "security", "s", "passthrough", "provide the security to use for proxy, comma separated to accept any of several: \napikey \nhttpbasic \noauth \noauthexternal \njwt \nauthpolicy \ntwowayssl \nawsheader \nawsquery \npassthrough")
+	cmd.Flags().StringVar(&authPolicy, "auth-policy", "", "policy invoked by the authpolicy and jwt security, a name in the policy library or an entity store key; jwt only names the device JWT, this policy must verify the token")
+	cmd.Flags().StringVar(&tokenInfoPolicy, "token-info-policy", "", "policy that validates external OAuth tokens for the oauthexternal security")
+	cmd.Flags().StringVar(&oauthScopes, "scopes", "create-orders", "OAuth scopes required by the oauth and oauthexternal security")
+	cmd.Flags().StringVar(&securityProfileFile, "security-profile-file", "", "yaml or json file describing the security profiles of the proxy")
+	cmd.Flags().StringVarP(&resourcePath, "resourcePath", "r", "", "provide the resource path for the proxy")
+	cmd.Flags().StringVarP(&certPath, "certPath", "c", "", "provide the location of the backend api cert")
+
+	cmd.Flags().StringVar(&corsProfileName, "cors-profile", "", "CORS profile of the library, the other --cors-* flags override its values")
+	addCorsFlags(cmd)
+	addBackendAuthFlags(cmd)
+
+	cmd.Flags().StringVarP(&proxyVersion, "proxyVersion", "v", "1.0", "provide the proxy version")
+	cmd.Flags().StringVarP(&proxyState, "proxyState", "p", "published", "provide the proxy state")
+	addCatalogFlags(cmd)
 }
 
 func createProxy(cmd *cobra.Command, args []string) {
+	if displayName != "" && proxyState != "published" {
+		// -n names the proxy until it is published under its display name
+		utils.PrettyPrintErr("--display-name is the name of the published proxy, use it with -p published or later with publish")
+		os.Exit(1)
+	}
 	cfg := getConfig()
-	proxyBody := apimgr.VirtualizedApi{}
 	client := &apimgr.APIClient{}
 	client = apimgr.NewAPIClient(cfg)
 
-	if resourcePath == "" {
-		fmt.Fprintln(os.Stderr, "Resource path is empty,so adding a random path ")
-		resourcePath = "/api/" + getUniqueID(5) + "/v1"
-	}
-	if certPath != "" {
-		certs, _ := importCerts(cfg, certPath)
-		proxyBody.CaCerts = certs
-	}
-
 	apiID := getAPIByName(args)
 
 	orgID := getOrganizationByName(args)
@@ -141,26 +218,18 @@ func createProxy(cmd *cobra.Command, args []string) {
 		return
 	}
 
-	switch security {
-	case "passthrough":
-		proxyBody.SecurityProfiles = getSecurityProfilePassThrough()
-	case "apikey":
-		proxyBody.SecurityProfiles = getSecurityProfileAPIKey()
-	case "httpbasic":
-		proxyBody.SecurityProfiles = getSecurityProfileHTTPBasic()
-	case "oauth":
-		proxyBody.SecurityProfiles = getSecurityProfileOAuth()
-	default:
-		fmt.Fprintln(os.Stderr, "Invalid security data - allowed security name: passthrough,apikey,oauth, httpbasic")
+	proxyBody, err := buildProxy(cmd, cfg, apimgr.VirtualizedApi{})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
 		return
 	}
-
-	proxyBody.Path = resourcePath //"/bank/v1"
 	proxyBody.ApiId = apiID
 	proxyBody.OrganizationId = orgID
-	proxyBody.Name = name
-	proxyBody.Version = proxyVersion
 	proxyBody.State = proxyState
+	if displayName != "" && proxyState == "published" {
+		// the proxy is renamed to the display name when it is published
+		proxyBody.State = "unpublished"
+	}
 
 	proxy, _, err := client.APIProxyRegistrationApi.ProxiesPost(context.Background(), proxyBody)
 	if err != nil {
@@ -168,13 +237,96 @@ func createProxy(cmd *cobra.Command, args []string) {
 		return
 	}
 	utils.PrettyPrintInfo("Proxy %v created", proxy.Name)
+	if displayName != "" && proxyState == "published" {
+		proxy.Name = displayName
+		if err = setProxyState(cfg, proxy, proxyState); err != nil {
+			utils.PrettyPrintErr("Error publishing proxy :%v", err)
+			return
+		}
+		utils.PrettyPrintInfo("Proxy published as %v", proxy.Name)
+	}
 	if appName != "" {
 		appID := getApplicationByName(args)
-		reqApplicationAPIAccess(appID, proxy.Id, cfg)
+		if _, err = reqApplicationAPIAccess(appID, proxy.Id, cfg); err != nil {
+			utils.PrettyPrintErr("Error granting %v access to Proxy %v: %v", appName, proxy.Name, err)
+			return
+		}
+		utils.PrettyPrintInfo("Application %v granted access to Proxy %v", appName, proxy.Name)
 	}
 	return
 }
 
+// buildProxy sets the fields given by the proxy flags on proxy. A new proxy gets the
+// defaults of the flags that are not given, an existing one keeps its values.
+func buildProxy(cmd *cobra.Command, cfg *apimgr.Configuration, proxy apimgr.VirtualizedApi) (apimgr.VirtualizedApi, error) {
+	creating := proxy.Id == ""
+	set := func(flag string) bool {
+		return creating || cmd.Flags().Changed(flag)
+	}
+	var err error
+
+	if set("resourcePath") {
+		if resourcePath == "" && creating {
+			fmt.Fprintln(os.Stderr, "Resource path is empty,so adding a random path ")
+			resourcePath = "/api/" + getUniqueID(5) + "/v1"
+		}
+		if resourcePath == "" {
+			return proxy, errors.New("resource path can't be empty")
+		}
+		proxy.Path = resourcePath //"/bank/v1"
+	}
+	if certPath != "" && set("certPath") {
+		proxy.CaCerts, err = importCerts(cfg, certPath)
+		if err != nil {
+			return proxy, err
+		}
+	}
+	if cmd.Flags().Changed("security-profile-file") {
+		if cmd.Flags().Changed("security") {
+			return proxy, errors.New("either --security or --security-profile-file is allowed")
+		}
+		proxy.SecurityProfiles, _, err = readSecurityProfiles(securityProfileFile)
+		if err != nil {
+			return proxy, err
+		}
+	} else if set("security") {
+		proxy.SecurityProfiles, err = getSecurityProfiles(security)
+		if err != nil {
+			return proxy, err
+		}
+	}
+	if cmd.Flags().Changed("backend-auth") {
+		auth, err := backendAuthFromFlags()
+		if err != nil {
+			return proxy, err
+		}
+		profile, err := auth.authenticationProfile(func(p string) string { return p })
+		if err != nil {
+			return proxy, err
+		}
+		setBackendAuth(&proxy, profile)
+	}
+	if corsFlagsChanged(cmd) {
+		profile, err := corsProfileFromFlags(cmd)
+		if err != nil {
+			return proxy, err
+		}
+		setProxyCors(&proxy, profile)
+	}
+	if set("proxyVersion") {
+		proxy.Version = proxyVersion
+	}
+	if err = setCatalogFields(cmd, &proxy); err != nil {
+		return proxy, err
+	}
+	if creating {
+		proxy.Name = name
+	} else if cmd.Flags().Changed("display-name") {
+		proxy.Name = displayName
+	}
+	return proxy, nil
+}
+
 func importCerts(cfg *apimgr.Configuration, certpath string) ([]apimgr.CaCert, error) {
 	client := &apimgr.APIClient{}
 	client = apimgr.NewAPIClient(cfg)
@@ -187,13 +339,16 @@ func importCerts(cfg *apimgr.Configuration, certpath string) ([]apimgr.CaCert, e
 	file, err := os.Open(certpath)
 	if err != nil {
 		utils.PrettyPrintErr("Unable to open the file: %v", err)
+		return nil, err
 	}
+	defer file.Close()
 
 	cerVar.File = optional.NewInterface(file)
 
 	certs, _, err := client.APIManagerServicesApi.CertinfoPost(context.Background(), &cerVar)
 	if err != nil {
 		utils.PrettyPrintErr("Error creating the cert: %v", err)
+		return nil, err
 	}
 	return certs, nil
 }
@@ -222,50 +377,122 @@ func getProxyByName(args []string, cfg *apimgr.Configuration) (apimgr.Virtualize
 	return proxy, errors.New("Proxy not found")
 }
 
+// findProxy returns the proxy with the given name, or nil when it doesn't exist
+func findProxy(cfg *apimgr.Configuration, name string) (*apimgr.VirtualizedApi, error) {
+	client := apimgr.NewAPIClient(cfg)
+
+	getProxyVars := &apimgr.ProxiesGetOpts{}
+
+	getProxyVars.Field = optional.NewInterface("name")
+	getProxyVars.Op = optional.NewInterface("eq")
+	getProxyVars.Value = optional.NewInterface(name)
+
+	proxies, _, err := client.APIProxyRegistrationApi.ProxiesGet(context.Background(), getProxyVars)
+	if err != nil {
+		return nil, err
+	}
+	if len(proxies) == 0 {
+		return nil, nil
+	}
+	return &proxies[0], nil
+}
+
 func listProxies(cmd *cobra.Command, args []string) {
 	cfg := getConfig()
 	client := &apimgr.APIClient{}
 	client = apimgr.NewAPIClient(cfg)
 	getProxyVars := &apimgr.ProxiesGetOpts{}
 
-	stdout := fmtDisplay()
 	proxies, _, err := client.APIProxyRegistrationApi.ProxiesGet(context.Background(), getProxyVars)
 	if err != nil {
 		utils.PrettyPrintErr("Error listing the proxies: %v", err)
 		return
 	}
-	if len(proxies) != 0 {
-		fmt.Fprintf(stdout, "ID\tNAME\tORGANIZATION\tPATH\tSTATE\tVERSION\n")
-		for _, proxy := range proxies {
-			org, _, _ := client.OrganizationsApi.OrganizationsIdGet(context.Background(), proxy.OrganizationId)
-			fmt.Fprintf(stdout, "%v\t%v\t%v\t%v\t%v\t%v\n", proxy.Id, proxy.Name, org.Name, proxy.Path, proxy.State, proxy.Version)
-		}
-		fmt.Fprint(stdout)
-		stdout.Flush()
+	if listVersions {
+		proxyVersionTable(cfg, proxies).printList("No Proxy found ")
 		return
 	}
-	utils.PrettyPrintInfo("No Proxy found ")
+	proxyTable(cfg, proxies).printList("No Proxy found ")
 	return
 }
 
+// proxyVersionTable lists the proxies grouped by name, the versions of a proxy newest first
+func proxyVersionTable(cfg *apimgr.Configuration, proxies []apimgr.VirtualizedApi) *resourceTable {
+	sorted := append([]apimgr.VirtualizedApi{}, proxies...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Name != sorted[j].Name {
+			return sorted[i].Name < sorted[j].Name
+		}
+		return compareVersions(sorted[i].Version, sorted[j].Version) > 0
+	})
+	orgNameOf := organizationNames(cfg)
+	t := newResourceTable("proxy",
+		[]string{"NAME", "VERSION", "LATEST", "STATE", "LIFECYCLE", "ORGANIZATION", "PATH", "ID"},
+		[]string{"BACKEND API ID", "SECURITY"})
+	for i, proxy := range sorted {
+		latest := i == 0 || sorted[i-1].Name != proxy.Name
+		t.addRow(proxy.Name, proxy, proxy.Name, proxy.Version, latest, proxy.State, proxyLifecycle(proxy), orgNameOf(proxy.OrganizationId),
+			proxy.Path, proxy.Id, proxy.ApiId, securityDeviceTypes(proxy.SecurityProfiles))
+	}
+	return t
+}
+
+// compareVersions compares dotted versions by their numeric parts, parts that
+// aren't numbers are compared as strings
+func compareVersions(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y string
+		if i < len(as) {
+			x = as[i]
+		}
+		if i < len(bs) {
+			y = bs[i]
+		}
+		xn, xErr := strconv.Atoi(x)
+		yn, yErr := strconv.Atoi(y)
+		switch {
+		case xErr == nil && yErr == nil && xn != yn:
+			if xn < yn {
+				return -1
+			}
+			return 1
+		case (xErr != nil || yErr != nil) && x != y:
+			return strings.Compare(x, y)
+		}
+	}
+	return 0
+}
+
+func proxyTable(cfg *apimgr.Configuration, proxies []apimgr.VirtualizedApi) *resourceTable {
+	orgNameOf := organizationNames(cfg)
+	t := newResourceTable("proxy",
+		[]string{"ID", "NAME", "ORGANIZATION", "PATH", "STATE", "LIFECYCLE", "VERSION"},
+		[]string{"VHOST", "BACKEND API ID", "SECURITY"})
+	for _, proxy := range proxies {
+		t.addRow(proxy.Name, proxy, proxy.Id, proxy.Name, orgNameOf(proxy.OrganizationId), proxy.Path, proxy.State, proxyLifecycle(proxy),
+			proxy.Version, proxy.Vhost, proxy.ApiId, securityDeviceTypes(proxy.SecurityProfiles))
+	}
+	return t
+}
+
 func deleteProxy(cmd *cobra.Command, args []string) {
+	checkSelection(cmd)
 	cfg := getConfig()
 
+	if selecting(cmd) {
+		runBulk(cfg, "Delete", func(proxy apimgr.VirtualizedApi) (string, error) {
+			return "deleted", removeProxy(cfg, proxy)
+		})
+		return
+	}
+
 	proxy, err := getProxyByName(args, cfg)
 	if err != nil {
 		utils.PrettyPrintErr("unable to find the proxy : %v", err)
 		return
 	}
-	if proxy.State == "published" {
-		fmt.Printf("Unable to Delete, Proxy %v is in published state \n", proxy.Name)
-		return
-	}
-
-	client := &apimgr.APIClient{}
-	client = apimgr.NewAPIClient(cfg)
-
-	_, err = client.APIProxyRegistrationApi.ProxiesIdDelete(context.Background(), proxy.Id)
-	if err != nil {
+	if err = removeProxy(cfg, proxy); err != nil {
 		utils.PrettyPrintErr("Unable to delete the Proxy: %v", err)
 		return
 	}
@@ -273,6 +500,32 @@ func deleteProxy(cmd *cobra.Command, args []string) {
 	return
 }
 
+// removeProxy deletes an unpublished proxy, a deprecated proxy that applications
+// still have access to is only deleted with --force
+func removeProxy(cfg *apimgr.Configuration, proxy apimgr.VirtualizedApi) error {
+	if proxy.State == "published" {
+		return fmt.Errorf("proxy %v is in published state", proxy.Name)
+	}
+	if proxy.Deprecated && !forceDelete {
+		apps, err := getProxyApplications(cfg, proxy.Id)
+		if err != nil {
+			return fmt.Errorf("unable to list the applications of the proxy: %v", err)
+		}
+		if len(apps) != 0 {
+			names := []string{}
+			for _, app := range apps {
+				names = append(names, app.Name)
+			}
+			return fmt.Errorf("deprecated proxy %v is used by %v, use --force to delete it", proxy.Name, strings.Join(names, ", "))
+		}
+	}
+
+	client := apimgr.NewAPIClient(cfg)
+
+	_, err := client.APIProxyRegistrationApi.ProxiesIdDelete(context.Background(), proxy.Id)
+	return err
+}
+
 func describeProxy(cmd *cobra.Command, args []string) {
 	cfg := getConfig()
 
@@ -294,10 +547,176 @@ func describeProxy(cmd *cobra.Command, args []string) {
 		utils.PrettyPrintErr("Unable to get the Proxy: %v", err)
 		return
 	}
-	prettyJSON, err := json.MarshalIndent(proxy, "", "    ")
+	proxyTable(cfg, []apimgr.VirtualizedApi{proxy}).printObject()
+	return
+}
+
+func editProxy(cmd *cobra.Command, args []string) {
+	cfg := getConfig()
+
+	editedProxy := apimgr.VirtualizedApi{}
+
+	client := apimgr.NewAPIClient(cfg)
+
+	proxy, err := getProxyByName(args, cfg)
+	if err != nil {
+		utils.PrettyPrintErr("unable to find the proxy : %v", err)
+		return
+	}
+	proxy, _, err = client.APIProxyRegistrationApi.ProxiesIdGet(context.Background(), proxy.Id)
+	if err != nil {
+		utils.PrettyPrintErr("Unable to get the Proxy: %v", err)
+		return
+	}
+	err = editObject("Proxy", proxy.Name, proxy, &editedProxy, func() error {
+		return checkImmutable("Proxy", proxy, editedProxy, proxyImmutableFields...)
+	})
+	if err == errEditCancelled {
+		utils.PrettyPrintInfo("%v", err)
+		return
+	}
 	if err != nil {
-		log.Fatal("Failed to marshal to json", err)
+		utils.PrettyPrintErr("%v", err)
+		return
 	}
-	fmt.Printf("%s\n", string(prettyJSON))
-	return
+	if reflect.DeepEqual(proxy, editedProxy) {
+		utils.PrettyPrintInfo("Proxy %v has no changes to update", editedProxy.Name)
+		return
+	}
+	updatedProxy, err := putProxy(cfg, proxy, editedProxy)
+	if err != nil {
+		utils.PrettyPrintErr("Error updating Proxy: %v", err)
+		return
+	}
+	utils.PrettyPrintInfo("Proxy %v updated with the valid changes", updatedProxy.Name)
+}
+
+func updateProxy(cmd *cobra.Command, args []string) {
+	cfg := getConfig()
+	client := apimgr.NewAPIClient(cfg)
+
+	proxy, err := getProxyByName(args, cfg)
+	if err != nil {
+		utils.PrettyPrintErr("unable to find the proxy : %v", err)
+		os.Exit(1)
+	}
+	proxy, _, err = client.APIProxyRegistrationApi.ProxiesIdGet(context.Background(), proxy.Id)
+	if err != nil {
+		utils.PrettyPrintErr("Unable to get the Proxy: %v", err)
+		os.Exit(1)
+	}
+	if cmd.Flags().Changed("orgName") && getOrganizationByName(args) != proxy.OrganizationId {
+		utils.PrettyPrintErr("The organization of proxy %v can't be changed", proxy.Name)
+		os.Exit(1)
+	}
+	if cmd.Flags().Changed("apiName") && getAPIByName(args) != proxy.ApiId {
+		utils.PrettyPrintErr("The backend API of proxy %v can't be changed", proxy.Name)
+		os.Exit(1)
+	}
+
+	updated, err := buildProxy(cmd, cfg, proxy)
+	if err != nil {
+		utils.PrettyPrintErr("%v", err)
+		os.Exit(1)
+	}
+	changed := !reflect.DeepEqual(proxy, updated)
+	if changed {
+		updated, err = putProxy(cfg, proxy, updated)
+		if err != nil {
+			utils.PrettyPrintErr("Error updating Proxy: %v", err)
+			os.Exit(1)
+		}
+	}
+	if cmd.Flags().Changed("proxyState") && proxyState != updated.State {
+		if err = setProxyState(cfg, updated, proxyState); err != nil {
+			utils.PrettyPrintErr("Error changing the state of Proxy %v: %v", updated.Name, err)
+			os.Exit(1)
+		}
+		changed = true
+	}
+	if appName != "" {
+		appID := getApplicationByName(args)
+		granted, err := hasApplicationAPIAccess(appID, updated.Id, cfg)
+		if err != nil {
+			utils.PrettyPrintErr("Error listing the api access of %v: %v", appName, err)
+			os.Exit(1)
+		}
+		if !granted {
+			if _, err = reqApplicationAPIAccess(appID, updated.Id, cfg); err != nil {
+				utils.PrettyPrintErr("Error granting %v access to Proxy %v: %v", appName, updated.Name, err)
+				os.Exit(1)
+			}
+			utils.PrettyPrintInfo("Application %v granted access to Proxy %v", appName, updated.Name)
+			changed = true
+		}
+	}
+	if !changed {
+		utils.PrettyPrintInfo("Proxy %v has no changes to update", proxy.Name)
+		return
+	}
+	utils.PrettyPrintInfo("Proxy %v updated", updated.Name)
+}
+
+// setProxyState publishes or unpublishes the proxy
+func setProxyState(cfg *apimgr.Configuration, proxy apimgr.VirtualizedApi, state string) error {
+	client := apimgr.NewAPIClient(cfg)
+
+	var err error
+	switch state {
+	case "published":
+		_, _, err = client.APIProxyRegistrationApi.ProxiesIdPublishPost(context.Background(), proxy.Id, proxy.Name, proxy.Vhost)
+	case "unpublished":
+		_, _, err = client.APIProxyRegistrationApi.ProxiesIdUnpublishPost(context.Background(), proxy.Id)
+	default:
+		err = fmt.Errorf("unsupported state %q", state)
+	}
+	return err
+}
+
+// putProxy puts the edited proxy. API Manager rejects most changes of a published
+// proxy, when it does the proxy is unpublished, updated and published again. Any
+// other error is returned as is and the proxy stays published.
+func putProxy(cfg *apimgr.Configuration, live, edited apimgr.VirtualizedApi) (apimgr.VirtualizedApi, error) {
+	client := apimgr.NewAPIClient(cfg)
+	ctx := context.Background()
+
+	updated, resp, err := client.APIProxyRegistrationApi.ProxiesIdPut(ctx, edited.Id, edited)
+	if err == nil || live.State != "published" || !publishedProxyRejected(resp, err) {
+		return updated, err
+	}
+
+	utils.PrettyPrintInfo("Proxy %v is published, unpublishing it to apply the changes", live.Name)
+	if _, _, err = client.APIProxyRegistrationApi.ProxiesIdUnpublishPost(ctx, live.Id); err != nil {
+		return live, fmt.Errorf("unable to unpublish the proxy: %v", err)
+	}
+	edited.State = "unpublished"
+	updated, _, err = client.APIProxyRegistrationApi.ProxiesIdPut(ctx, edited.Id, edited)
+	if err != nil {
+		// publish the unchanged proxy again
+		updated = live
+	}
+	published, _, pubErr := client.APIProxyRegistrationApi.ProxiesIdPublishPost(ctx, live.Id, updated.Name, updated.Vhost)
+	if err != nil {
+		if pubErr != nil {
+			return live, fmt.Errorf("%v, the proxy is left unpublished: %v", err, pubErr)
+		}
+		return live, err
+	}
+	if pubErr != nil {
+		return updated, fmt.Errorf("proxy updated but left unpublished: %v", pubErr)
+	}
+	return published, nil
+}
+
+// publishedProxyRejected reports whether API Manager refused to update a proxy
+// because it is published, the response is a client error explaining it
+func publishedProxyRejected(resp *http.Response, err error) bool {
+	if resp == nil || resp.StatusCode != http.StatusBadRequest && resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusConflict {
+		return false
+	}
+	swaggerErr, ok := err.(apimgr.GenericSwaggerError)
+	if !ok {
+		return false
+	}
+	return strings.Contains(strings.ToLower(string(swaggerErr.Body())), "published")
 }
@@ -26,7 +26,10 @@ import (
 	"github.com/spf13/viper"
 )
 
-var cfgFile string
+var (
+	cfgFile     string
+	contextName string
+)
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
@@ -55,6 +58,7 @@ func init() {
 	// will be global for your application.
 
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.apimanager.yaml)")
+	rootCmd.PersistentFlags().StringVar(&contextName, "context", "", "name of the API Manager context to use")
 
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
@@ -1,12 +1,199 @@
 package cmd
 
 import (
+	"bytes"
+	"encoding/xml"
+	"errors"
 	"fmt"
+	"io/ioutil"
+	"strings"
 
 	"github.com/skckadiyala/apimanager/apimgr"
-	"github.com/spf13/viper"
+	"gopkg.in/yaml.v2"
 )
 
+var (
+	securityProfileFile string
+	authPolicy          string
+	tokenInfoPolicy     string
+	oauthScopes         string
+)
+
+// builtinSecurityProfiles are the names of the security profiles built from flags
+var builtinSecurityProfiles = []string{"apikey", "authpolicy", "awsheader", "awsquery", "httpbasic", "jwt", "oauth", "oauthexternal", "passthrough", "twowayssl"}
+
+// getSecurityProfiles returns the security profiles for the given security names. Several
+// comma separated names, like apikey,oauth, give a default profile that accepts any of
+// their devices, in the given order.
+func getSecurityProfiles(security string) ([]apimgr.SecurityProfile, error) {
+	names := strings.Split(security, ",")
+	if len(names) == 1 {
+		return getSecurityProfile(security)
+	}
+	devices := []apimgr.SecurityDevice{}
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "passthrough" {
+			return nil, errors.New("passthrough can't be combined with other security")
+		}
+		profiles, err := getSecurityProfile(name)
+		if err != nil {
+			return nil, err
+		}
+		for _, profile := range profiles {
+			if profile.IsDefault || len(profiles) == 1 {
+				devices = append(devices, profile.Devices...)
+			}
+		}
+	}
+	for i := range devices {
+		devices[i].Order = int32(i + 1)
+	}
+	return []apimgr.SecurityProfile{{Name: "_default", IsDefault: true, Devices: devices}}, nil
+}
+
+// getSecurityProfile returns the security profiles for the given security name, a
+// profile of the library in the config file is used before the built-in ones
+func getSecurityProfile(security string) ([]apimgr.SecurityProfile, error) {
+	conf, err := loadConfigFile()
+	if err != nil {
+		return nil, err
+	}
+	if value, ok := conf.SecurityProfiles[security]; ok {
+		profiles, err := decodeSecurityProfiles(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid security profile %v in the config file: %v", security, err)
+		}
+		return profiles, nil
+	}
+	switch security {
+	case "authpolicy", "jwt":
+		if authPolicy == "" {
+			return nil, fmt.Errorf("%v security requires the policy to invoke, use --auth-policy", security)
+		}
+	case "oauthexternal":
+		if tokenInfoPolicy == "" {
+			return nil, errors.New("oauthexternal security requires the token information policy, use --token-info-policy")
+		}
+	}
+	if profiles, ok := builtinSecurityProfile(security); ok {
+		return profiles, nil
+	}
+	return nil, fmt.Errorf("Invalid security data - allowed security name: %v or a profile of 'config get-security-profiles'", strings.Join(builtinSecurityProfiles, ", "))
+}
+
+// builtinSecurityProfile builds the security profile with the given name from the flags
+func builtinSecurityProfile(security string) ([]apimgr.SecurityProfile, bool) {
+	switch security {
+	case "passthrough":
+		return getSecurityProfilePassThrough(), true
+	case "apikey":
+		return getSecurityProfileAPIKey(), true
+	case "httpbasic":
+		return getSecurityProfileHTTPBasic(), true
+	case "oauth":
+		return getSecurityProfileOAuth(), true
+	case "oauthexternal":
+		return getSecurityProfileOAuthExternal(), true
+	case "authpolicy":
+		return getSecurityProfileAuthPolicy("Invoke Policy"), true
+	case "jwt":
+		// jwt is an authpolicy device named JWT, it doesn't validate the token itself,
+		// the policy given by --auth-policy must verify it
+		return getSecurityProfileAuthPolicy("JWT"), true
+	case "twowayssl":
+		return getSecurityProfileTwoWaySSL(), true
+	case "awsheader":
+		return getSecurityProfileAWS("awsHeader", "AWS Signature (header)"), true
+	case "awsquery":
+		return getSecurityProfileAWS("awsQuery", "AWS Signature (query string)"), true
+	}
+	return nil, false
+}
+
+// securityDeviceTypes returns the device types of the profiles
+func securityDeviceTypes(profiles []apimgr.SecurityProfile) string {
+	types := []string{}
+	for _, profile := range profiles {
+		for _, device := range profile.Devices {
+			types = append(types, device.Type)
+		}
+	}
+	return strings.Join(types, ",")
+}
+
+// readSecurityProfiles reads the security profiles described in a yaml or json file
+func readSecurityProfiles(fileName string) ([]apimgr.SecurityProfile, interface{}, error) {
+	content, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, nil, err
+	}
+	var value interface{}
+	if err = yaml.Unmarshal(content, &value); err != nil {
+		return nil, nil, fmt.Errorf("invalid security profile file %v: %v", fileName, err)
+	}
+	value = jsonValue(value)
+	profiles, err := decodeSecurityProfiles(value)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid security profile file %v: %v", fileName, err)
+	}
+	return profiles, value, nil
+}
+
+// decodeSecurityProfiles decodes a single security profile or a list of them. A single
+// profile without a name is the default profile. Device properties may be any scalar.
+func decodeSecurityProfiles(value interface{}) ([]apimgr.SecurityProfile, error) {
+	value = jsonValue(value)
+	list, ok := value.([]interface{})
+	if !ok {
+		list = []interface{}{value}
+	}
+	for _, profile := range list {
+		profile, ok := profile.(map[string]interface{})
+		if !ok {
+			return nil, errors.New("a security profile must be a mapping")
+		}
+		devices, _ := profile["devices"].([]interface{})
+		for _, device := range devices {
+			device, _ := device.(map[string]interface{})
+			props, _ := device["properties"].(map[string]interface{})
+			for key, prop := range props {
+				if prop == nil {
+					props[key] = ""
+				} else {
+					props[key] = fmt.Sprint(prop)
+				}
+			}
+		}
+	}
+
+	profiles := []apimgr.SecurityProfile{}
+	if err := fromYAMLValue(list, &profiles); err != nil {
+		return nil, err
+	}
+	if len(profiles) == 1 && profiles[0].Name == "" {
+		profiles[0].Name = "_default"
+		profiles[0].IsDefault = true
+	}
+	for i, profile := range profiles {
+		if profile.Name == "" {
+			return nil, fmt.Errorf("security profile %v has no name", i+1)
+		}
+		if len(profile.Devices) == 0 {
+			return nil, fmt.Errorf("security profile %v has no devices", profile.Name)
+		}
+		for j, device := range profile.Devices {
+			if device.Type == "" {
+				return nil, fmt.Errorf("device %v of security profile %v has no type", j+1, profile.Name)
+			}
+			if device.Order == 0 {
+				profiles[i].Devices[j].Order = int32(j + 1)
+			}
+		}
+	}
+	return profiles, nil
+}
+
 func getSecurityProfilePassThrough() []apimgr.SecurityProfile {
 	securityProfile := make([]apimgr.SecurityProfile, 1)
 	device := make([]apimgr.SecurityDevice, 1)
@@ -75,7 +262,8 @@ func getSecurityProfileHTTPBasic() []apimgr.SecurityProfile {
 
 func getSecurityProfileOAuth() []apimgr.SecurityProfile {
 
-	apiHost := viper.GetString("apimanagerhost")
+	_, api, _ := currentConfig()
+	apiHost := api.APIManagerHost
 	securityProfile := make([]apimgr.SecurityProfile, 1)
 	device := make([]apimgr.SecurityDevice, 1)
 
@@ -88,7 +276,7 @@ func getSecurityProfileOAuth() []apimgr.SecurityProfile {
 		"authorizationHeaderPrefix":               "Bearer",
 		"accessTokenLocationQueryString":          "",
 		"scopesMustMatch":                         "Any",
-		"scopes":                                  "create-orders",
+		"scopes":                                  oauthScopes,
 		"removeCredentialsOnSuccess":              "true",
 		"implicitGrantEnabled":                    "true",
 		"implicitGrantLoginEndpointUrl":           endPointURL,
@@ -112,3 +300,118 @@ func getSecurityProfileOAuth() []apimgr.SecurityProfile {
 
 	return securityProfile
 }
+
+func getSecurityProfileOAuthExternal() []apimgr.SecurityProfile {
+	securityProfile := make([]apimgr.SecurityProfile, 1)
+	device := make([]apimgr.SecurityDevice, 1)
+
+	props := map[string]string{
+		"tokenStore":                     policyKey(tokenInfoPolicy),
+		"useClientRegistry":              "true",
+		"subjectSelector":                "${oauth.token.client_id}",
+		"oauth.token.client_id":          "${oauth.token.client_id}",
+		"oauth.token.scopes":             "${oauth.token.scopes}",
+		"oauth.token.valid":              "${oauth.token.valid}",
+		"accessTokenLocation":            "HEADER",
+		"authorizationHeaderPrefix":      "Bearer",
+		"accessTokenLocationQueryString": "",
+		"scopesMustMatch":                "Any",
+		"scopes":                         oauthScopes,
+		"removeCredentialsOnSuccess":     "true",
+	}
+
+	device[0].Name = "OAuth (External)"
+	device[0].Type = "oauthExternal"
+	device[0].Order = 1
+	device[0].Properties = props
+
+	securityProfile[0].Devices = device
+	securityProfile[0].IsDefault = true
+	securityProfile[0].Name = "_default"
+
+	return securityProfile
+}
+
+func getSecurityProfileAuthPolicy(deviceName string) []apimgr.SecurityProfile {
+	securityProfile := make([]apimgr.SecurityProfile, 1)
+	device := make([]apimgr.SecurityDevice, 1)
+
+	props := map[string]string{
+		"authenticationPolicy":       policyKey(authPolicy),
+		"useClientRegistry":          "true",
+		"subjectSelector":            "${authentication.subject.id}",
+		"descriptionType":            "original",
+		"removeCredentialsOnSuccess": "true",
+	}
+
+	device[0].Name = deviceName
+	device[0].Type = "authPolicy"
+	device[0].Order = 1
+	device[0].Properties = props
+
+	securityProfile[0].Devices = device
+	securityProfile[0].IsDefault = true
+	securityProfile[0].Name = "_default"
+
+	return securityProfile
+}
+
+func getSecurityProfileTwoWaySSL() []apimgr.SecurityProfile {
+	securityProfile := make([]apimgr.SecurityProfile, 1)
+	device := make([]apimgr.SecurityDevice, 1)
+
+	props := map[string]string{
+		"subjectSelector":            "${http.request.clientcert.subjectDN}",
+		"removeCredentialsOnSuccess": "true",
+	}
+
+	device[0].Name = "Two-way SSL"
+	device[0].Type = "twoWaySSL"
+	device[0].Order = 1
+	device[0].Properties = props
+
+	securityProfile[0].Devices = device
+	securityProfile[0].IsDefault = true
+	securityProfile[0].Name = "_default"
+
+	return securityProfile
+}
+
+func getSecurityProfileAWS(deviceType, deviceName string) []apimgr.SecurityProfile {
+	securityProfile := make([]apimgr.SecurityProfile, 1)
+	device := make([]apimgr.SecurityDevice, 1)
+
+	props := map[string]string{
+		"credentialsLocation":        "HEADER",
+		"removeCredentialsOnSuccess": "true",
+	}
+	if deviceType == "awsQuery" {
+		props["credentialsLocation"] = "QUERY"
+	}
+
+	device[0].Name = deviceName
+	device[0].Type = deviceType
+	device[0].Order = 1
+	device[0].Properties = props
+
+	securityProfile[0].Devices = device
+	securityProfile[0].IsDefault = true
+	securityProfile[0].Name = "_default"
+
+	return securityProfile
+}
+
+// policyKey returns the entity store key of a policy given by name, a key is returned as is
+func policyKey(policy string) string {
+	if strings.HasPrefix(policy, "<key") {
+		return policy
+	}
+	return "<key type='CircuitContainer'><id field='name' value='Policy Library'/><key type='FilterCircuit'><id field='name' value='" + xmlAttr(policy) + "'/></key></key>"
+}
+
+// xmlAttr escapes a value for an attribute of an entity store key
+func xmlAttr(value string) string {
+	var b bytes.Buffer
+	xml.EscapeText(&b, []byte(value))
+	return b.String()
+}
@@ -0,0 +1,34 @@
+/*
+Copyright © 2020 Axway, Inc. <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import "testing"
+
+func TestPolicyKey(t *testing.T) {
+	tests := []struct {
+		policy, want string
+	}{
+		{"Verify JWT", "<key type='CircuitContainer'><id field='name' value='Policy Library'/><key type='FilterCircuit'><id field='name' value='Verify JWT'/></key></key>"},
+		{"Tony's <JWT> & co", "<key type='CircuitContainer'><id field='name' value='Policy Library'/><key type='FilterCircuit'><id field='name' value='Tony&#39;s &lt;JWT&gt; &amp; co'/></key></key>"},
+		{"<key type='FilterCircuit'/>", "<key type='FilterCircuit'/>"},
+	}
+	for _, test := range tests {
+		if got := policyKey(test.policy); got != test.want {
+			t.Errorf("policyKey(%q) = %q, want %q", test.policy, got, test.want)
+		}
+	}
+}
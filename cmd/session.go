@@ -0,0 +1,287 @@
+/*
+Copyright © 2020 Axway, Inc. <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// authentication modes of a context
+const (
+	authBasic   = "basic"   // Basic credentials on every request
+	authSession = "session" // login once and send the session cookie
+	authAuto    = "auto"    // session, Basic when the session login is rejected
+)
+
+const (
+	csrfHeader = "CSRF-Token"
+	// sessionSuffix is appended to the context name to cache its session in the credential store
+	sessionSuffix = "/session"
+)
+
+var authMode string
+
+// session is a logged in API Manager session
+type session struct {
+	Cookies   map[string]string `json:"cookies"`
+	CSRFToken string            `json:"csrfToken,omitempty"`
+}
+
+// sessionTransport sends the requests with the session of the context, it logs
+// in when there is no session yet and again when the session expired
+type sessionTransport struct {
+	base        http.RoundTripper
+	loginURL    string
+	name        string
+	api         configAPI
+	mu          sync.Mutex
+	current     *session
+	loaded      bool
+	useBasic    bool
+	loginClient *http.Client
+}
+
+func newSessionTransport(base http.RoundTripper, loginURL, name string, api configAPI) *sessionTransport {
+	return &sessionTransport{
+		base:     base,
+		loginURL: loginURL,
+		name:     name,
+		api:      api,
+		loginClient: &http.Client{
+			Transport: base,
+			// the login answers with a redirect to the portal, the session is in that response
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+func (t *sessionTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// the body is sent again when the session expired
+	if req.Body != nil && req.GetBody == nil {
+		content, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.GetBody = func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewReader(content)), nil
+		}
+	}
+
+	s, err := t.session(nil)
+	if err != nil {
+		return nil, err
+	}
+	if s == nil {
+		return t.base.RoundTrip(t.withBasic(req))
+	}
+	sessionReq, err := t.withSession(req, s)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := t.base.RoundTrip(sessionReq)
+	if err != nil || !sessionExpired(resp) {
+		return resp, err
+	}
+
+	// the session expired, login again and retry once
+	resp.Body.Close()
+	if s, err = t.session(s); err != nil {
+		return nil, err
+	}
+	if s == nil {
+		return t.base.RoundTrip(t.withBasic(req))
+	}
+	if sessionReq, err = t.withSession(req, s); err != nil {
+		return nil, err
+	}
+	return t.base.RoundTrip(sessionReq)
+}
+
+// sessionExpired reports whether the request was refused because of the session, a
+// 403 is only a stale session when it is about the CSRF token or the session, other
+// 403 are permission errors that a new login doesn't change
+func sessionExpired(resp *http.Response) bool {
+	if resp.StatusCode == http.StatusUnauthorized {
+		return true
+	}
+	if resp.StatusCode != http.StatusForbidden {
+		return false
+	}
+	content, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = ioutil.NopCloser(bytes.NewReader(content))
+	if err != nil {
+		return false
+	}
+	body := strings.ToLower(string(content))
+	for _, marker := range []string{"csrf", "session expired", "session has expired", "invalid session"} {
+		if strings.Contains(body, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// session returns the session to send the requests with, nil when Basic credentials
+// are used. A new session is created when there is none or stale is the current one.
+func (t *sessionTransport) session(stale *session) (*session, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.useBasic {
+		return nil, nil
+	}
+	if !t.loaded {
+		t.loaded = true
+		t.current = loadSession(t.name, t.api)
+	}
+	if t.current != nil && t.current != stale {
+		return t.current, nil
+	}
+	s, err := t.login()
+	if err != nil {
+		if t.api.AuthMode == authAuto {
+			t.useBasic = true
+			return nil, nil
+		}
+		return nil, err
+	}
+	t.current = s
+	saveSession(t.name, t.api, s)
+	return s, nil
+}
+
+// login posts the context credentials to the API Manager login endpoint
+func (t *sessionTransport) login() (*session, error) {
+	username, password, err := basicCredentials(t.api.Authorization)
+	if err != nil {
+		return nil, err
+	}
+	form := url.Values{"username": {username}, "password": {password}}
+	resp, err := t.loginClient.PostForm(t.loginURL, form)
+	if err != nil {
+		return nil, fmt.Errorf("session login failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("session login failed: %s", resp.Status)
+	}
+	s := &session{Cookies: map[string]string{}, CSRFToken: resp.Header.Get(csrfHeader)}
+	for _, cookie := range resp.Cookies() {
+		s.Cookies[cookie.Name] = cookie.Value
+	}
+	if len(s.Cookies) == 0 {
+		return nil, fmt.Errorf("session login failed: no session cookie returned")
+	}
+	return s, nil
+}
+
+func (t *sessionTransport) withSession(req *http.Request, s *session) (*http.Request, error) {
+	r, err := cloneRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	r.Header.Del("Authorization")
+	for name, value := range s.Cookies {
+		r.AddCookie(&http.Cookie{Name: name, Value: value})
+	}
+	if s.CSRFToken != "" {
+		r.Header.Set(csrfHeader, s.CSRFToken)
+	}
+	return r, nil
+}
+
+func (t *sessionTransport) withBasic(req *http.Request) *http.Request {
+	r, err := cloneRequest(req)
+	if err != nil {
+		r = req.Clone(req.Context())
+	}
+	r.Header.Set("Authorization", "Basic "+t.api.Authorization)
+	return r
+}
+
+// cloneRequest copies the request with a fresh body, a RoundTripper must not change the request
+func cloneRequest(req *http.Request) (*http.Request, error) {
+	r := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		r.Body = body
+	}
+	return r, nil
+}
+
+// basicCredentials decodes the username and password of a Basic token
+func basicCredentials(token string) (string, string, error) {
+	content, err := base64.StdEncoding.DecodeString(token)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid stored credentials, use 'login' command")
+	}
+	parts := strings.SplitN(string(content), ":", 2)
+	if len(parts) != 2 {
+		return "", "", fmt.Errorf("invalid stored credentials, use 'login' command")
+	}
+	return parts[0], parts[1], nil
+}
+
+// loadSession returns the cached session of the context, nil when there is none
+func loadSession(name string, api configAPI) *session {
+	if name == "" {
+		return nil
+	}
+	content, err := getCredentialStore(api).get(name + sessionSuffix)
+	if err != nil || content == "" {
+		return nil
+	}
+	s := &session{}
+	if err = json.Unmarshal([]byte(content), s); err != nil || len(s.Cookies) == 0 {
+		return nil
+	}
+	return s
+}
+
+// saveSession caches the session of the context, a session that can't be cached
+// is only used by the running command
+func saveSession(name string, api configAPI, s *session) {
+	if name == "" {
+		return
+	}
+	content, err := json.Marshal(s)
+	if err != nil {
+		return
+	}
+	getCredentialStore(api).store(name+sessionSuffix, string(content))
+}
+
+// eraseSession removes the cached session of the context
+func eraseSession(name string, api configAPI) error {
+	return getCredentialStore(api).erase(name + sessionSuffix)
+}
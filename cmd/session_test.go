@@ -0,0 +1,51 @@
+/*
+Copyright © 2020 Axway, Inc. <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestSessionExpired(t *testing.T) {
+	tests := []struct {
+		status int
+		body   string
+		want   bool
+	}{
+		{http.StatusOK, "", false},
+		{http.StatusUnauthorized, "", true},
+		{http.StatusForbidden, `{"errors":[{"message":"Invalid CSRF token"}]}`, true},
+		{http.StatusForbidden, "Your session has expired", true},
+		{http.StatusForbidden, "Invalid session", true},
+		{http.StatusForbidden, `{"errors":[{"message":"Forbidden, you can't change this organization"}]}`, false},
+		{http.StatusForbidden, "", false},
+		{http.StatusInternalServerError, "session expired", false},
+	}
+	for _, test := range tests {
+		resp := &http.Response{StatusCode: test.status, Body: ioutil.NopCloser(strings.NewReader(test.body))}
+		if got := sessionExpired(resp); got != test.want {
+			t.Errorf("sessionExpired(%v, %q) = %v, want %v", test.status, test.body, got, test.want)
+		}
+		// the body is kept for the caller
+		if content, _ := ioutil.ReadAll(resp.Body); string(content) != test.body {
+			t.Errorf("sessionExpired(%v, %q) left the body %q", test.status, test.body, content)
+		}
+	}
+}
@@ -18,13 +18,28 @@ package cmd
 
 import (
 	"context"
+	"encoding/base64"
 	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
 
 	"github.com/skckadiyala/apimanager/apimgr"
 	"github.com/skckadiyala/kubecrt-vms/utils"
 	"github.com/spf13/cobra"
 )
 
+// API Catalog flags of publish, create proxy and update proxy
+var (
+	vhost           string
+	displayName     string
+	catalogTags     []string
+	summary         string
+	descriptionFile string
+)
+
 // unpublishCmd represents the unpublish command
 var (
 	unpublishCmd = &cobra.Command{
@@ -33,7 +48,14 @@ var (
 		Short:   "unpublish a proxy",
 		Long: `unpublish the proxy For example:
 
-apimanager unpublish -p <proxy Name>`,
+apimanager unpublish -n <proxy Name>
+
+# Unpublish all the proxies of an organization without confirmation
+apimanager unpublish --org <orgName> --yes
+
+Proxies are selected with --org, --tag, --state, --name-regex, --all or
+--from-file instead of -n, a proxy has to match all of them. The selected
+proxies are listed and changed after confirmation, --parallel at a time.`,
 		Run: unpublishProxy,
 	}
 	publishCmd = &cobra.Command{
@@ -42,7 +64,20 @@ apimanager unpublish -p <proxy Name>`,
 		Short:   "publish a proxy",
 		Long: `publish the proxy For example:
 
-apimanager publish -p <proxy Name>`,
+apimanager publish -n <proxy Name>
+
+# Publish with a complete API Catalog entry
+apimanager publish -n <proxy Name> --display-name 'Bank API' --vhost api.example.com --tag domain=banking --tag domain=payments --summary 'Accounts and payments' --description-file bank.md -i bank.png
+
+The proxy is renamed to --display-name. The catalog flags of a published proxy
+are applied without unpublishing it when API Manager allows it.
+
+# Publish the unpublished proxies tagged team=avengers
+apimanager publish --tag team=avengers --state unpublished
+
+Proxies are selected with --org, --tag, --state, --name-regex, --all or
+--from-file instead of -n, a proxy has to match all of them. Without -n, --tag
+selects proxies and the other catalog flags can't be used.`,
 		Run: publishProxy,
 	}
 )
@@ -52,18 +87,82 @@ func init() {
 	rootCmd.AddCommand(publishCmd)
 
 	unpublishCmd.Flags().StringVarP(&name, "name", "n", "", "proxy name")
-	unpublishCmd.MarkFlagRequired("name")
+	addSelectorFlags(unpublishCmd, true)
 
 	publishCmd.Flags().StringVarP(&name, "name", "n", "", "proxy name")
-	publishCmd.MarkFlagRequired("name")
+	addCatalogFlags(publishCmd)
+	addSelectorFlags(publishCmd, false)
+}
+
+// addCatalogFlags adds the flags of the API Catalog entry of a proxy
+func addCatalogFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&vhost, "vhost", "", "virtual host the proxy is published on")
+	cmd.Flags().StringVar(&displayName, "display-name", "", "name of the proxy in the API Catalog, the proxy is renamed when published")
+	cmd.Flags().StringArrayVar(&catalogTags, "tag", nil, "API Catalog tag as key=value, repeat for several tags or values")
+	cmd.Flags().StringVar(&summary, "summary", "", "summary of the proxy in the API Catalog")
+	cmd.Flags().StringVar(&descriptionFile, "description-file", "", "markdown file with the description of the proxy in the API Catalog")
+	cmd.Flags().StringVarP(&image, "image", "i", "", "image file of the proxy in the API Catalog")
+}
+
+// setCatalogFields sets the API Catalog fields given by the catalog flags on the
+// proxy, the values of the tag keys given are replaced
+func setCatalogFields(cmd *cobra.Command, proxy *apimgr.VirtualizedApi) error {
+	if cmd.Flags().Changed("vhost") {
+		proxy.Vhost = vhost
+	}
+	if cmd.Flags().Changed("summary") {
+		proxy.Summary = summary
+	}
+	if cmd.Flags().Changed("description-file") {
+		description, err := ioutil.ReadFile(descriptionFile)
+		if err != nil {
+			return err
+		}
+		proxy.DescriptionType = "manual"
+		proxy.DescriptionManual = string(description)
+	}
+	if cmd.Flags().Changed("tag") {
+		given := map[string][]string{}
+		for _, tag := range catalogTags {
+			parts := strings.SplitN(tag, "=", 2)
+			if len(parts) != 2 || parts[0] == "" {
+				return fmt.Errorf("invalid tag %q, use key=value", tag)
+			}
+			given[parts[0]] = append(given[parts[0]], parts[1])
+		}
+		tags := map[string][]string{}
+		for key, values := range proxy.Tags {
+			tags[key] = values
+		}
+		for key, values := range given {
+			tags[key] = values
+		}
+		proxy.Tags = tags
+	}
+	if cmd.Flags().Changed("image") {
+		content, err := ioutil.ReadFile(image)
+		if err != nil {
+			return err
+		}
+		proxy.Image = "data:" + http.DetectContentType(content) + ";base64," + base64.StdEncoding.EncodeToString(content)
+	}
+	return nil
 }
 
 // (proxyID string, cfg *apimgr.Configuration)
 func unpublishProxy(cmd *cobra.Command, args []string) {
+	checkSelection(cmd)
 	cfg := getConfig()
 	client := &apimgr.APIClient{}
 	client = apimgr.NewAPIClient(cfg)
 
+	if selecting(cmd) {
+		runBulk(cfg, "Unpublish", func(proxy apimgr.VirtualizedApi) (string, error) {
+			return changeProxyState(cfg, proxy, "unpublished")
+		})
+		return
+	}
+
 	proxy, err := getProxyByName(args, cfg)
 	if err != nil {
 		utils.PrettyPrintErr("Proxy %v not found %v", name, err)
@@ -78,20 +177,80 @@ func unpublishProxy(cmd *cobra.Command, args []string) {
 }
 
 func publishProxy(cmd *cobra.Command, args []string) {
-
+	checkSelection(cmd)
 	cfg := getConfig()
 	client := &apimgr.APIClient{}
 	client = apimgr.NewAPIClient(cfg)
 
+	if selecting(cmd) {
+		for _, flag := range []string{"vhost", "display-name", "summary", "description-file", "image"} {
+			if cmd.Flags().Changed(flag) {
+				utils.PrettyPrintErr("--%v sets the catalog entry of one proxy, use -n", flag)
+				os.Exit(1)
+			}
+		}
+		runBulk(cfg, "Publish", func(proxy apimgr.VirtualizedApi) (string, error) {
+			return changeProxyState(cfg, proxy, "published")
+		})
+		return
+	}
+
 	proxy, err := getProxyByName(args, cfg)
 	if err != nil {
 		utils.PrettyPrintErr("Proxy %v not found %v", name, err)
+		os.Exit(1)
 	}
-	_, _, err = client.APIProxyRegistrationApi.ProxiesIdPublishPost(context.Background(), proxy.Id, proxy.Name, "")
+	proxy, _, err = client.APIProxyRegistrationApi.ProxiesIdGet(context.Background(), proxy.Id)
 	if err != nil {
-		utils.PrettyPrintErr("Error Updating the Proxy: %v", err)
+		utils.PrettyPrintErr("Unable to get the Proxy: %v", err)
+		os.Exit(1)
+	}
+	updated := proxy
+	if err = setCatalogFields(cmd, &updated); err != nil {
+		utils.PrettyPrintErr("%v", err)
+		os.Exit(1)
+	}
+	if displayName != "" {
+		updated.Name = displayName
+	}
+	if proxy.State == "published" {
+		if reflect.DeepEqual(proxy, updated) {
+			utils.PrettyPrintInfo("Proxy %v is already published", proxy.Name)
+			return
+		}
+		if _, err = putProxy(cfg, proxy, updated); err != nil {
+			utils.PrettyPrintErr("Error Updating the Proxy: %v", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Proxy %v published \n", updated.Name)
 		return
 	}
-	fmt.Printf("Proxy %v published \n", proxy.Name)
+
+	// the name and the virtual host are given to the publish operation
+	published := updated
+	updated.Name, updated.Vhost = proxy.Name, proxy.Vhost
+	if !reflect.DeepEqual(proxy, updated) {
+		if _, _, err = client.APIProxyRegistrationApi.ProxiesIdPut(context.Background(), proxy.Id, updated); err != nil {
+			utils.PrettyPrintErr("Error Updating the Proxy: %v", err)
+			os.Exit(1)
+		}
+	}
+	_, _, err = client.APIProxyRegistrationApi.ProxiesIdPublishPost(context.Background(), proxy.Id, published.Name, published.Vhost)
+	if err != nil {
+		utils.PrettyPrintErr("Error Updating the Proxy: %v", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Proxy %v published \n", published.Name)
 	return
 }
+
+// changeProxyState publishes or unpublishes the proxy and returns the result for runBulk
+func changeProxyState(cfg *apimgr.Configuration, proxy apimgr.VirtualizedApi, state string) (string, error) {
+	if proxy.State == state {
+		return "already " + state, nil
+	}
+	if err := setProxyState(cfg, proxy, state); err != nil {
+		return "", err
+	}
+	return state, nil
+}
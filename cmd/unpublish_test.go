@@ -0,0 +1,68 @@
+/*
+Copyright © 2020 Axway, Inc. <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/skckadiyala/apimanager/apimgr"
+	"github.com/spf13/cobra"
+)
+
+func TestSetCatalogFieldsTags(t *testing.T) {
+	live := map[string][]string{"team": {"avengers"}, "stage": {"beta"}}
+	tests := []struct {
+		name    string
+		args    []string
+		want    map[string][]string
+		wantErr bool
+	}{
+		{"no tag flag", []string{"--summary", "Shield"}, live, false},
+		{"new key", []string{"--tag", "domain=banking"},
+			map[string][]string{"team": {"avengers"}, "stage": {"beta"}, "domain": {"banking"}}, false},
+		{"replaced key", []string{"--tag", "stage=ga"},
+			map[string][]string{"team": {"avengers"}, "stage": {"ga"}}, false},
+		{"several values", []string{"--tag", "team=shield", "--tag", "team=hydra", "--tag", "domain=banking"},
+			map[string][]string{"team": {"shield", "hydra"}, "stage": {"beta"}, "domain": {"banking"}}, false},
+		{"empty value", []string{"--tag", "stage="},
+			map[string][]string{"team": {"avengers"}, "stage": {""}}, false},
+		{"value with =", []string{"--tag", "motto=a=b"},
+			map[string][]string{"team": {"avengers"}, "stage": {"beta"}, "motto": {"a=b"}}, false},
+		{"no value", []string{"--tag", "team"}, nil, true},
+		{"no key", []string{"--tag", "=avengers"}, nil, true},
+	}
+	for _, test := range tests {
+		cmd := &cobra.Command{}
+		addCatalogFlags(cmd)
+		if err := cmd.Flags().Parse(test.args); err != nil {
+			t.Fatalf("%v: %v", test.name, err)
+		}
+		proxy := apimgr.VirtualizedApi{Tags: live}
+		err := setCatalogFields(cmd, &proxy)
+		if (err != nil) != test.wantErr {
+			t.Errorf("%v: error = %v, want error %v", test.name, err, test.wantErr)
+			continue
+		}
+		if !test.wantErr && !reflect.DeepEqual(proxy.Tags, test.want) {
+			t.Errorf("%v: tags = %v, want %v", test.name, proxy.Tags, test.want)
+		}
+		if !reflect.DeepEqual(live, map[string][]string{"team": {"avengers"}, "stage": {"beta"}}) {
+			t.Fatalf("%v: tags of the live proxy changed to %v", test.name, live)
+		}
+	}
+}
@@ -0,0 +1,464 @@
+/*
+Copyright © 2020 Axway, Inc. <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/antihax/optional"
+	"github.com/skckadiyala/apimanager/apimgr"
+	"github.com/skckadiyala/kubecrt-vms/utils"
+	"github.com/spf13/cobra"
+)
+
+// system quotas of API Manager
+const (
+	applicationDefaultQuotaID = "00000000-0000-0000-0000-000000000000"
+	systemQuotaID             = "00000000-0000-0000-0000-000000000001"
+)
+
+var (
+	fromVersion  string
+	newName      string
+	deprecateOld bool
+	retireOld    bool
+	retireDate   string
+	keepAccess   bool
+	keepQuotas   bool
+	keepSecurity bool
+	listVersions bool
+
+	// create proxy defaults these, the upgrade defaults come from the old proxy
+	upgradeVersion  string
+	upgradePath     string
+	upgradeState    string
+	upgradeSecurity string
+)
+
+// upgradeCmd represents the upgrade command
+var (
+	upgradeCmd = &cobra.Command{
+		Use:   "upgrade",
+		Short: "Upgrade an API Manager resource to a new version",
+	}
+	proxyUpgrade = &cobra.Command{
+		Use:   "proxy",
+		Short: "Upgrade a proxy to a new backend API",
+		Long: `Upgrade a proxy to a new backend API. A new version of the proxy is created
+from the new backend API with the settings of the old proxy, and the applications
+of the old proxy are given access to the new one with the API Manager upgrade
+operation. Subscriptions are kept, the old proxy isn't deleted. When a step fails
+after the new proxy is created, it is kept and the steps done and its ID are printed.
+
+The old proxy is deprecated with --deprecate and retired with --retire, on
+--retire-date or now. Application access, quotas and security are carried over
+unless --keep-access, --keep-quotas or --keep-security is false.
+
+For example:
+
+# Upgrade a proxy to version 2.0 of its backend API
+apimanager upgrade proxy -n 'Civil War' --api 'Captain America v2' -v 2.0
+
+# Deprecate the old version now and retire it at the end of the year
+apimanager upgrade proxy -n 'Civil War' --api 'Captain America v2' -v 2.0 --deprecate --retire --retire-date 2026-12-31
+
+# Secure the new version with OAuth instead of the security of the old one
+apimanager upgrade proxy -n 'Civil War' --api 'Captain America v2' -v 2.0 --keep-security=false -s oauth`,
+		Run: upgradeProxy,
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(upgradeCmd)
+	upgradeCmd.AddCommand(proxyUpgrade)
+
+	proxyUpgrade.Flags().StringVarP(&name, "name", "n", "", "proxy name")
+	proxyUpgrade.Flags().StringVar(&fromVersion, "from-version", "", "version of the proxy to upgrade when several have the name")
+	proxyUpgrade.Flags().StringVar(&apiName, "api", "", "the new backend API name")
+	proxyUpgrade.Flags().StringVarP(&upgradeVersion, "proxyVersion", "v", "", "version of the new proxy")
+	proxyUpgrade.Flags().StringVar(&newName, "new-name", "", "name of the new proxy, the name of the old one by default")
+	proxyUpgrade.Flags().StringVarP(&upgradePath, "resourcePath", "r", "", "resource path of the new proxy, the path of the old one by default")
+	proxyUpgrade.Flags().StringVarP(&upgradeState, "proxyState", "p", "", "state of the new proxy, the state of the old one by default")
+	proxyUpgrade.Flags().BoolVar(&deprecateOld, "deprecate", false, "deprecate the old proxy")
+	proxyUpgrade.Flags().BoolVar(&retireOld, "retire", false, "retire the old proxy on --retire-date, or now")
+	proxyUpgrade.Flags().StringVar(&retireDate, "retire-date", "", "retirement date of the old proxy, YYYY-MM-DD")
+	proxyUpgrade.Flags().BoolVar(&keepAccess, "keep-access", true, "give the applications of the old proxy access to the new one")
+	proxyUpgrade.Flags().BoolVar(&keepQuotas, "keep-quotas", true, "copy the quotas of the old proxy to the new one")
+	proxyUpgrade.Flags().BoolVar(&keepSecurity, "keep-security", true, "copy the security, CORS and backend authentication of the old proxy")
+	proxyUpgrade.Flags().StringVarP(&upgradeSecurity, "security", "s", "", "security of the new proxy when --keep-security is false")
+	proxyUpgrade.MarkFlagRequired("name")
+	proxyUpgrade.MarkFlagRequired("api")
+}
+
+func upgradeProxy(cmd *cobra.Command, args []string) {
+	if keepSecurity && cmd.Flags().Changed("security") {
+		utils.PrettyPrintErr("-s requires --keep-security=false, the security of the old proxy is copied otherwise")
+		os.Exit(1)
+	}
+
+	cfg := getConfig()
+	client := apimgr.NewAPIClient(cfg)
+	ctx := context.Background()
+
+	old, err := findProxyVersion(cfg, name, fromVersion)
+	if err != nil {
+		utils.PrettyPrintErr("%v", err)
+		os.Exit(1)
+	}
+	old, _, err = client.APIProxyRegistrationApi.ProxiesIdGet(ctx, old.Id)
+	if err != nil {
+		utils.PrettyPrintErr("Unable to get the Proxy: %v", err)
+		os.Exit(1)
+	}
+	apiID, err := resolveBackendAPIID(cfg, apiName)
+	if err != nil {
+		utils.PrettyPrintErr("%v", err)
+		os.Exit(1)
+	}
+	retirement, err := parseRetireDate(retireDate)
+	if err != nil {
+		utils.PrettyPrintErr("%v", err)
+		os.Exit(1)
+	}
+	if retirement.IsZero() && retireOld {
+		retirement = time.Now()
+	}
+	if !retirement.IsZero() && !retireOld {
+		utils.PrettyPrintErr("--retire-date requires --retire")
+		os.Exit(1)
+	}
+
+	proxy, err := newProxyVersion(old, apiID)
+	if err != nil {
+		utils.PrettyPrintErr("%v", err)
+		os.Exit(1)
+	}
+	upgraded, _, err := client.APIProxyRegistrationApi.ProxiesPost(ctx, proxy)
+	if err != nil {
+		utils.PrettyPrintErr("Error creating proxy :%v", err)
+		os.Exit(1)
+	}
+	utils.PrettyPrintInfo("Proxy %v version %v created", upgraded.Name, upgraded.Version)
+
+	// the new proxy is kept when a later step fails, applications may already use it,
+	// the steps done and its ID tell what is left to finish or delete
+	done := []string{fmt.Sprintf("proxy %v version %v created with ID %v", upgraded.Name, upgraded.Version, upgraded.Id)}
+	fail := func(format string, a ...interface{}) {
+		utils.PrettyPrintErr(format, a...)
+		utils.PrettyPrintErr("Upgrade incomplete, done: %v. Finish the remaining steps on proxy ID %v or delete it", strings.Join(done, "; "), upgraded.Id)
+		os.Exit(1)
+	}
+
+	if keepSecurity {
+		created := upgraded
+		if err = copyMethodProfiles(cfg, old, &upgraded); err == nil && !reflect.DeepEqual(created, upgraded) {
+			upgraded, err = putProxy(cfg, created, upgraded)
+		}
+		if err != nil {
+			fail("Unable to copy the method settings of the old proxy: %v", err)
+		}
+		done = append(done, "method settings copied")
+	}
+	state := upgradeState
+	if state == "" {
+		state = old.State
+	}
+	if state != upgraded.State {
+		if err = setProxyState(cfg, upgraded, state); err != nil {
+			fail("Error changing the state of Proxy %v: %v", upgraded.Name, err)
+		}
+		done = append(done, "state set to "+state)
+	}
+
+	if keepAccess {
+		err = upgradeProxyAccess(cfg, old.Id, upgraded.Id, deprecateOld, retireOld, retirement)
+		if err != nil {
+			fail("Unable to upgrade the access to the new proxy: %v", err)
+		}
+		done = append(done, "application access upgraded")
+		utils.PrettyPrintInfo("Application access upgraded to version %v", upgraded.Version)
+	} else if deprecateOld || retireOld {
+		if err = deprecateProxy(cfg, old.Id, retirement); err != nil {
+			fail("Unable to deprecate the old proxy: %v", err)
+		}
+		done = append(done, "old proxy deprecated")
+	}
+	if keepQuotas {
+		if err = copyQuotas(cfg, old, upgraded); err != nil {
+			fail("Unable to copy the quotas of the old proxy: %v", err)
+		}
+	}
+	if deprecateOld || retireOld {
+		lifecycle := "deprecated"
+		if retireOld {
+			lifecycle += ", retiring on " + retirement.Format("2006-01-02")
+		}
+		utils.PrettyPrintInfo("Proxy %v version %v %v", old.Name, old.Version, lifecycle)
+	}
+	utils.PrettyPrintInfo("Proxy %v upgraded to version %v", old.Name, upgraded.Version)
+}
+
+// findProxyVersion returns the proxy with the name and version, any version when
+// version is empty as long as only one proxy has the name
+func findProxyVersion(cfg *apimgr.Configuration, name, version string) (apimgr.VirtualizedApi, error) {
+	client := apimgr.NewAPIClient(cfg)
+
+	getProxyVars := &apimgr.ProxiesGetOpts{}
+	getProxyVars.Field = optional.NewInterface("name")
+	getProxyVars.Op = optional.NewInterface("eq")
+	getProxyVars.Value = optional.NewInterface(name)
+
+	proxies, _, err := client.APIProxyRegistrationApi.ProxiesGet(context.Background(), getProxyVars)
+	if err != nil {
+		return apimgr.VirtualizedApi{}, fmt.Errorf("error getting the proxy: %v", err)
+	}
+	found := []apimgr.VirtualizedApi{}
+	versions := []string{}
+	for _, proxy := range proxies {
+		if version == "" || proxy.Version == version {
+			found = append(found, proxy)
+			versions = append(versions, proxy.Version)
+		}
+	}
+	switch {
+	case len(found) == 0 && version != "":
+		return apimgr.VirtualizedApi{}, fmt.Errorf("proxy %v version %v not found", name, version)
+	case len(found) == 0:
+		return apimgr.VirtualizedApi{}, fmt.Errorf("proxy %v not found", name)
+	case len(found) > 1:
+		return apimgr.VirtualizedApi{}, fmt.Errorf("proxy %v has versions %v, use --from-version", name, strings.Join(versions, ", "))
+	}
+	return found[0], nil
+}
+
+// newProxyVersion returns a new proxy for the backend API with the settings of old
+func newProxyVersion(old apimgr.VirtualizedApi, apiID string) (apimgr.VirtualizedApi, error) {
+	proxy := old
+	proxy.Id, proxy.CreatedOn, proxy.CreatedBy = "", 0, ""
+	proxy.ApiId = apiID
+	proxy.State = "unpublished"
+	proxy.Deprecated, proxy.Retired, proxy.Expired, proxy.RetirementDate = false, false, false, 0
+	if newName != "" {
+		proxy.Name = newName
+	}
+	if upgradeVersion != "" {
+		proxy.Version = upgradeVersion
+	}
+	if upgradePath != "" {
+		proxy.Path = upgradePath
+	}
+	if proxy.Name == old.Name && proxy.Version == old.Version {
+		return proxy, fmt.Errorf("proxy %v version %v exists, use -v to give the new version", old.Name, old.Version)
+	}
+
+	// method settings are keyed by the method ids of the old proxy, they are
+	// copied by copyMethodProfiles once the new proxy exists
+	proxy.InboundProfiles = map[string]apimgr.InboundProfile{}
+	if inbound, ok := old.InboundProfiles["_default"]; ok {
+		proxy.InboundProfiles["_default"] = inbound
+	}
+	proxy.OutboundProfiles = map[string]apimgr.OutboundProfile{}
+	if outbound, ok := old.OutboundProfiles["_default"]; ok {
+		outbound.ApiId, outbound.ApiMethodId = apiID, ""
+		proxy.OutboundProfiles["_default"] = outbound
+	}
+	proxy.ServiceProfiles = map[string]apimgr.ServiceProfile{}
+	for key, service := range old.ServiceProfiles {
+		if service.ApiId == old.ApiId {
+			service.ApiId = apiID
+		}
+		proxy.ServiceProfiles[key] = service
+	}
+
+	if !keepSecurity {
+		if upgradeSecurity == "" {
+			return proxy, fmt.Errorf("the security of the new proxy is required, use -s")
+		}
+		profiles, err := getSecurityProfiles(upgradeSecurity)
+		if err != nil {
+			return proxy, err
+		}
+		proxy.SecurityProfiles = profiles
+		proxy.CorsProfiles, proxy.AuthenticationProfiles = nil, nil
+		proxy.InboundProfiles = map[string]apimgr.InboundProfile{"_default": {SecurityProfile: "_default"}}
+		outbound := proxy.OutboundProfiles["_default"]
+		outbound.AuthenticationProfile = ""
+		proxy.OutboundProfiles["_default"] = outbound
+	}
+	return proxy, nil
+}
+
+// copyMethodProfiles copies the method settings of old to the methods of proxy,
+// methods are matched by name
+func copyMethodProfiles(cfg *apimgr.Configuration, old apimgr.VirtualizedApi, proxy *apimgr.VirtualizedApi) error {
+	if len(old.InboundProfiles) <= 1 && len(old.OutboundProfiles) <= 1 {
+		return nil
+	}
+	methodIDs, backendMethodIDs, err := methodIDMaps(cfg, old, *proxy)
+	if err != nil {
+		return err
+	}
+
+	inboundProfiles := map[string]apimgr.InboundProfile{}
+	for key, inbound := range proxy.InboundProfiles {
+		inboundProfiles[key] = inbound
+	}
+	for key, inbound := range old.InboundProfiles {
+		if newKey, ok := methodIDs[key]; ok {
+			inboundProfiles[newKey] = inbound
+		}
+	}
+	outboundProfiles := map[string]apimgr.OutboundProfile{}
+	for key, outbound := range proxy.OutboundProfiles {
+		outboundProfiles[key] = outbound
+	}
+	for key, outbound := range old.OutboundProfiles {
+		newKey, ok := methodIDs[key]
+		if !ok {
+			continue
+		}
+		if outbound.ApiId == old.ApiId {
+			outbound.ApiId = proxy.ApiId
+			outbound.ApiMethodId = backendMethodIDs[outbound.ApiMethodId]
+		}
+		outboundProfiles[newKey] = outbound
+	}
+	proxy.InboundProfiles = inboundProfiles
+	proxy.OutboundProfiles = outboundProfiles
+	return nil
+}
+
+// methodIDMaps maps the method ids of the old proxy and its backend API to the
+// ids of the methods with the same names of the new proxy and its backend API
+func methodIDMaps(cfg *apimgr.Configuration, old, proxy apimgr.VirtualizedApi) (map[string]string, map[string]string, error) {
+	oldOperations, oldBackendMethods, err := proxyMethods(cfg, old)
+	if err != nil {
+		return nil, nil, err
+	}
+	operations, backendMethods, err := proxyMethods(cfg, proxy)
+	if err != nil {
+		return nil, nil, err
+	}
+	methodIDs := map[string]string{}
+	for _, oldOperation := range oldOperations {
+		for _, operation := range operations {
+			if operation.Name == oldOperation.Name {
+				methodIDs[oldOperation.Id] = operation.Id
+			}
+		}
+	}
+	backendMethodIDs := map[string]string{}
+	for _, oldMethod := range oldBackendMethods {
+		if method, ok := methodByName(backendMethods, oldMethod.Name); ok {
+			backendMethodIDs[oldMethod.Id] = method.Id
+		}
+	}
+	return methodIDs, backendMethodIDs, nil
+}
+
+// upgradeProxyAccess gives the applications of the old proxy access to the new one,
+// and deprecates or retires the old proxy
+func upgradeProxyAccess(cfg *apimgr.Configuration, oldID, newID string, deprecate, retire bool, retirement time.Time) error {
+	form := url.Values{}
+	form.Set("upgradeApiId", newID)
+	form.Set("deprecate", strconv.FormatBool(deprecate))
+	form.Set("retire", strconv.FormatBool(retire))
+	if retire {
+		form.Set("retirementDate", formatRetirementDate(retirement))
+	}
+	_, err := apiRequest(cfg, "POST", "/proxies/upgrade/"+oldID, strings.NewReader(form.Encode()), "application/x-www-form-urlencoded")
+	return err
+}
+
+// copyQuotas adds the restrictions of the old proxy to the new one in the system
+// quotas and the quotas of the applications of the new proxy
+func copyQuotas(cfg *apimgr.Configuration, old, proxy apimgr.VirtualizedApi) error {
+	apps, err := getProxyApplications(cfg, proxy.Id)
+	if err != nil {
+		return err
+	}
+	paths := []string{"/quotas/" + applicationDefaultQuotaID, "/quotas/" + systemQuotaID}
+	for _, app := range apps {
+		paths = append(paths, "/applications/"+app.Id+"/quota")
+	}
+
+	var methodIDs map[string]string
+	for _, path := range paths {
+		quota := apimgr.QuotaDefinition{}
+		content, err := apiRequest(cfg, "GET", path, nil, "")
+		if err == nil {
+			err = json.Unmarshal(content, &quota)
+		}
+		if err != nil {
+			return fmt.Errorf("unable to get the quota %v: %v", path, err)
+		}
+
+		added := false
+		for _, restriction := range quota.Restrictions {
+			if restriction.Api != old.Id {
+				continue
+			}
+			restriction.Api = proxy.Id
+			if restriction.Method != "*" {
+				if methodIDs == nil {
+					if methodIDs, _, err = methodIDMaps(cfg, old, proxy); err != nil {
+						return err
+					}
+				}
+				method, ok := methodIDs[restriction.Method]
+				if !ok {
+					continue
+				}
+				restriction.Method = method
+			}
+			if hasRestriction(quota.Restrictions, restriction) {
+				continue
+			}
+			quota.Restrictions = append(quota.Restrictions, restriction)
+			added = true
+		}
+		if !added {
+			continue
+		}
+		content, err = json.Marshal(quota)
+		if err != nil {
+			return err
+		}
+		if _, err = apiRequest(cfg, "PUT", path, bytes.NewReader(content), "application/json"); err != nil {
+			return fmt.Errorf("unable to update the quota %v: %v", path, err)
+		}
+	}
+	return nil
+}
+
+func hasRestriction(restrictions []apimgr.QuotaRestriction, restriction apimgr.QuotaRestriction) bool {
+	for _, r := range restrictions {
+		if reflect.DeepEqual(r, restriction) {
+			return true
+		}
+	}
+	return false
+}
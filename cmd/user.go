@@ -22,7 +22,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
-	"log"
 	"os"
 	"reflect"
 
@@ -235,14 +234,32 @@ func getUserByName(args []string) string {
 
 }
 
+// findUser returns the user with the given name, or nil when it doesn't exist
+func findUser(cfg *apimgr.Configuration, name string) (*apimgr.User, error) {
+	client := apimgr.NewAPIClient(cfg)
+
+	getUserVars := &apimgr.UsersGetOpts{}
+
+	getUserVars.Field = optional.NewInterface("name")
+	getUserVars.Op = optional.NewInterface("eq")
+	getUserVars.Value = optional.NewInterface(name)
+
+	users, _, err := client.UsersApi.UsersGet(context.Background(), getUserVars)
+	if err != nil {
+		return nil, err
+	}
+	if len(users) == 0 {
+		return nil, nil
+	}
+	return &users[0], nil
+}
+
 func listUsers(cmd *cobra.Command, args []string) {
 
 	cfg := getConfig()
 	client := &apimgr.APIClient{}
 	client = apimgr.NewAPIClient(cfg)
 
-	stdout := fmtDisplay()
-
 	getUserVars := &apimgr.UsersGetOpts{}
 
 	users, _, err := client.UsersApi.UsersGet(context.Background(), getUserVars)
@@ -250,18 +267,18 @@ func listUsers(cmd *cobra.Command, args []string) {
 		utils.PrettyPrintErr("Error listing the users: %v", err)
 		return
 	}
-	if len(users) != 0 {
-		fmt.Fprintf(stdout, "ID\tNAME\tLOGIN\tORGANIZATION\tEMAIL\tROLE\n")
-		for _, user := range users {
-			org, _, _ := client.OrganizationsApi.OrganizationsIdGet(context.Background(), user.OrganizationId)
-			fmt.Fprintf(stdout, "%v\t%v\t%v\t%v\t%v\t%v\n", user.Id, user.Name, user.LoginName, org.Name, user.Email, user.Role)
-		}
-		fmt.Fprint(stdout)
-		stdout.Flush()
-	} else {
-		utils.PrettyPrintInfo("No users found ")
-		return
+	userTable(cfg, users).printList("No users found ")
+}
+
+func userTable(cfg *apimgr.Configuration, users []apimgr.User) *resourceTable {
+	orgNameOf := organizationNames(cfg)
+	t := newResourceTable("user",
+		[]string{"ID", "NAME", "LOGIN", "ORGANIZATION", "EMAIL", "ROLE"},
+		[]string{"PHONE", "ENABLED", "STATE"})
+	for _, user := range users {
+		t.addRow(user.Name, user, user.Id, user.Name, user.LoginName, orgNameOf(user.OrganizationId), user.Email, user.Role, user.Phone, user.Enabled, user.State)
 	}
+	return t
 }
 
 func deleteUser(cmd *cobra.Command, args []string) {
@@ -282,11 +299,10 @@ func deleteUser(cmd *cobra.Command, args []string) {
 
 func describeUser(cmd *cobra.Command, args []string) {
 	user, err := descUser(cmd, args)
-	prettyJSON, err := json.MarshalIndent(user, "", "    ")
 	if err != nil {
-		log.Fatal("Failed to marshal to json", err)
+		return
 	}
-	fmt.Printf("%s\n", string(prettyJSON))
+	userTable(getConfig(), []apimgr.User{user}).printObject()
 	return
 }
 
@@ -295,7 +311,6 @@ func editUser(cmd *cobra.Command, args []string) {
 
 	editedUser := apimgr.User{}
 
-	fname := getUniqueID(5)
 	client := &apimgr.APIClient{}
 	client = apimgr.NewAPIClient(cfg)
 
@@ -303,41 +318,30 @@ func editUser(cmd *cobra.Command, args []string) {
 	if err != nil {
 		return
 	}
-	prettyJSON, err := json.MarshalIndent(user, "", "    ")
-	if err != nil {
-		log.Fatal("Failed to marshal org to json", err)
-		return
-	}
-
-	err = createTempFile(fname, prettyJSON)
-	if err != nil {
+	err = editObject("User", user.Name, user, &editedUser, func() error {
+		return checkImmutable("User", user, editedUser, userImmutableFields...)
+	})
+	if err == errEditCancelled {
+		utils.PrettyPrintInfo("%v", err)
 		return
 	}
-
-	editedObj, _ := ioutil.ReadFile("/tmp/" + fname)
-	err = json.Unmarshal(editedObj, &editedUser)
 	if err != nil {
-		fmt.Println("Invalid json object, no changes applied")
+		utils.PrettyPrintErr("%v", err)
 		return
 	}
 	userVars := &apimgr.UsersIdPutOpts{}
 	userVars.Body = optional.NewInterface(editedUser)
 
-	if editedUser.Id != user.Id || editedUser.CreatedOn != user.CreatedOn {
-		fmt.Println("User can't be updated with the changed values")
-		return
-	}
 	if reflect.DeepEqual(user, editedUser) {
 		utils.PrettyPrintInfo("User %v has no changes to update", editedUser.Name)
 		return
 	}
 	updatedUser, _, err := client.UsersApi.UsersIdPut(context.Background(), editedUser.Id, userVars)
 	if err != nil {
-		utils.PrettyPrintErr("Error updating User")
+		utils.PrettyPrintErr("Error updating User: %v", err)
 		return
 	}
 	utils.PrettyPrintInfo("User %v updated with the valid changes", updatedUser.Name)
-	deleteTempFile(fname)
 	return
 
 }